@@ -0,0 +1,25 @@
+package codec
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldComment returns the comment documenting a struct field for a sample configuration. It is
+// made of the `comment` tag, the values allowed by the oneof rule of the `validate` tag and the
+// value of the `default` tag, one per line.
+func FieldComment(field reflect.StructField) string {
+	var lines []string
+	if comment := field.Tag.Get("comment"); comment != "" {
+		lines = append(lines, comment)
+	}
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if values, ok := strings.CutPrefix(rule, "oneof="); ok {
+			lines = append(lines, "One of: "+strings.Join(strings.Fields(values), ", "))
+		}
+	}
+	if value, ok := field.Tag.Lookup("default"); ok && value != "-" {
+		lines = append(lines, "Default: "+value)
+	}
+	return strings.Join(lines, "\n")
+}
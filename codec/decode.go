@@ -0,0 +1,134 @@
+// Package codec holds the pipeline shared by the json, yaml and toml packages. Decoding stores a
+// decoded document tree into a value with mapstructure.
+//
+// Besides the mapstructure tags, a struct field may list the old names of its key in a
+// `deprecated` tag, e.g. `mapstructure:"address" deprecated:"host"`. The old keys are still
+// accepted, case-insensitively like the other keys, with a warning logged by slog.
+//
+// When a profile is selected by DecodeOption, a document may keep variations of itself in a
+// profiles block, e.g. [profiles.prod] in TOML. The active profile is deep-merged over the
+// document and the profiles block is removed before decoding.
+//
+// A Registry decodes the values of an interface into the concrete types named by a discriminator
+// key, e.g. type = "file" in TOML, with its DecodeHook. The codecs encode the discriminators back
+// with their Unions option.
+package codec
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DecodeOption is a type for functional options for the DecodeWith functions of the codecs.
+type DecodeOption struct {
+	Hooks  []mapstructure.DecodeHookFunc
+	Limits Limits
+	// Source names the document in warnings, e.g. of deprecated keys. It defaults to the name of
+	// the reader if it has one, e.g. *os.File.
+	Source string
+	// Lines returns the line of a key in the document, given the keys and indices of its path
+	// from the root, or 0 if it is unknown. The codecs set it to locate the keys in warnings.
+	Lines func(keys []string) int
+	// Profile is the name of the section in the profiles block to deep-merge over the document.
+	Profile string
+	// ProfileEnv is the environment variable naming the profile if Profile is empty.
+	ProfileEnv string
+	// Tree forces decoding through the document tree, even if the codec could decode the
+	// document directly into the value, see Direct.
+	Tree bool
+}
+
+// NewDecodeOption applies the functional options to a new DecodeOption.
+func NewDecodeOption(opts ...func(*DecodeOption)) DecodeOption {
+	var opt DecodeOption
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	return opt
+}
+
+// WithHooks appends decode hooks to the DecodeOption.
+func WithHooks(hooks ...mapstructure.DecodeHookFunc) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Hooks = append(opt.Hooks, hooks...)
+	}
+}
+
+// WithLimits sets the Limits of the DecodeOption.
+func WithLimits(limits Limits) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Limits = limits
+	}
+}
+
+// WithSource sets the Source of the DecodeOption.
+func WithSource(source string) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Source = source
+	}
+}
+
+// WithProfile sets the Profile of the DecodeOption.
+func WithProfile(profile string) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.Profile = profile
+	}
+}
+
+// WithProfileEnv sets the ProfileEnv of the DecodeOption.
+func WithProfileEnv(env string) func(*DecodeOption) {
+	return func(opt *DecodeOption) {
+		opt.ProfileEnv = env
+	}
+}
+
+// ReadAll reads the whole document from the reader within the Limits, and names the Source after
+// the reader if it is not set.
+func (opt *DecodeOption) ReadAll(reader io.Reader) ([]byte, error) {
+	if named, ok := reader.(interface{ Name() string }); ok && opt.Source == "" {
+		opt.Source = named.Name()
+	}
+	return opt.Limits.ReadAll(reader)
+}
+
+// Decode stores the decoded document tree data in the value pointed to by result. All the
+// problems are reported at once in a DecodeError.
+func Decode(data any, result any, opt DecodeOption) error {
+	if err := opt.Limits.Check(data); err != nil {
+		return err
+	}
+	if profile, ok := opt.profile(); ok {
+		var err error
+		if data, err = applyProfile(data, profile); err != nil {
+			return err
+		}
+	}
+	if typ := reflect.TypeOf(result); typ != nil {
+		var errs []FieldError
+		if data, errs = (renamer{source: opt.Source, lines: opt.Lines}).rename(data, typ, "", nil); len(errs) > 0 {
+			return DecodeError{Errors: errs}
+		}
+	}
+
+	var hookErrs []error
+	hook := mapstructure.ComposeDecodeHookFunc(opt.Hooks...)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: func(from reflect.Value, to reflect.Value) (any, error) {
+			data, err := mapstructure.DecodeHookExec(hook, from, to)
+			if err != nil {
+				hookErrs = append(hookErrs, err)
+			}
+			return data, err
+		},
+		Result: result,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(data); err != nil {
+		return newDecodeError(err, hookErrs)
+	}
+	return nil
+}
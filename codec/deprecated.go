@@ -0,0 +1,178 @@
+package codec
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// deprecatedTypes caches whether a type has deprecated keys in its fields.
+var deprecatedTypes sync.Map
+
+// hasDeprecated reports whether the type, or any type nested in it, has a field with a
+// deprecated tag.
+func hasDeprecated(typ reflect.Type) bool {
+	if cached, ok := deprecatedTypes.Load(typ); ok {
+		return cached.(bool)
+	}
+	found := findDeprecated(typ, map[reflect.Type]bool{})
+	deprecatedTypes.Store(typ, found)
+	return found
+}
+
+func findDeprecated(typ reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[typ] {
+		return false
+	}
+	visited[typ] = true
+
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return findDeprecated(typ.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.IsExported() && (field.Tag.Get("deprecated") != "" || findDeprecated(field.Type, visited)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renamer moves the values of deprecated keys in a document tree to the keys of the fields
+// declaring them in their `deprecated` tags, e.g. `deprecated:"old_name,older_name"`.
+type renamer struct {
+	source string
+	lines  func(keys []string) int
+}
+
+// rename returns a copy of the document tree with the deprecated keys renamed, leaving data
+// untouched. The keys are matched case-insensitively like mapstructure does. A warning is logged
+// for each deprecated key, and it is an error to set both keys.
+func (r renamer) rename(data any, typ reflect.Type, path string, keys []string) (any, []FieldError) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if !hasDeprecated(typ) {
+		return data, nil
+	}
+
+	var errs []FieldError
+	switch typ.Kind() {
+	case reflect.Struct:
+		if data, ok := data.(map[string]any); ok {
+			renamed := maps.Clone(data)
+			errs = r.renameStruct(renamed, typ, path, keys)
+			return renamed, errs
+		}
+	case reflect.Slice, reflect.Array:
+		if data, ok := data.([]any); ok {
+			renamed := make([]any, len(data))
+			for i, value := range data {
+				index := strconv.Itoa(i)
+				var valueErrs []FieldError
+				renamed[i], valueErrs = r.rename(value, typ.Elem(), path+"["+index+"]", appendKey(keys, index))
+				errs = append(errs, valueErrs...)
+			}
+			return renamed, errs
+		}
+	case reflect.Map:
+		if data, ok := data.(map[string]any); ok {
+			renamed := make(map[string]any, len(data))
+			for key, value := range data {
+				var valueErrs []FieldError
+				renamed[key], valueErrs = r.rename(value, typ.Elem(), path+"["+key+"]", appendKey(keys, key))
+				errs = append(errs, valueErrs...)
+			}
+			return renamed, errs
+		}
+	}
+	return data, nil
+}
+
+// renameStruct renames the deprecated keys of the fields of the struct type in place in data,
+// which must be a copy.
+func (r renamer) renameStruct(data map[string]any, typ reflect.Type, path string, keys []string) []FieldError {
+	var errs []FieldError
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "squash") {
+			// The types which can't be squashed are left to mapstructure to report.
+			squashed := field.Type
+			for squashed.Kind() == reflect.Pointer {
+				squashed = squashed.Elem()
+			}
+			if squashed.Kind() == reflect.Struct {
+				errs = append(errs, r.renameStruct(data, squashed, path, keys)...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		current, hasCurrent := lookupKey(data, name)
+		if tag := field.Tag.Get("deprecated"); tag != "" {
+			for _, old := range strings.Split(tag, ",") {
+				old, ok := lookupKey(data, old)
+				if !ok {
+					continue
+				}
+				if hasCurrent {
+					errs = append(errs, FieldError{
+						Path:  joinPath(path, old),
+						Cause: fmt.Errorf("deprecated key conflicts with %s", joinPath(path, current)),
+					})
+					continue
+				}
+				args := []any{"source", r.source}
+				if r.lines != nil {
+					if line := r.lines(appendKey(keys, old)); line > 0 {
+						args = append(args, "line", line)
+					}
+				}
+				slog.Warn("deprecated config key",
+					append(args, "key", joinPath(path, old), "replacement", joinPath(path, name))...)
+				data[name] = data[old]
+				delete(data, old)
+				current, hasCurrent = name, true
+			}
+		}
+		if hasCurrent {
+			var valueErrs []FieldError
+			data[current], valueErrs = r.rename(data[current], field.Type, joinPath(path, current), appendKey(keys, current))
+			errs = append(errs, valueErrs...)
+		}
+	}
+	return errs
+}
+
+// lookupKey returns the key of data matching the name, exactly or else case-insensitively.
+func lookupKey(data map[string]any, name string) (string, bool) {
+	if _, ok := data[name]; ok {
+		return name, true
+	}
+	for key := range data {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// appendKey returns a new path of keys, leaving the backing array of keys untouched.
+func appendKey(keys []string, key string) []string {
+	return append(keys[:len(keys):len(keys)], key)
+}
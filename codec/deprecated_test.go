@@ -0,0 +1,171 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type deprecatedServer struct {
+	Address string `mapstructure:"address" deprecated:"host,hostname"`
+}
+
+type DeprecatedEmbedded struct {
+	Timeout int `mapstructure:"timeout" deprecated:"wait"`
+}
+
+type deprecatedConfig struct {
+	Level              string             `mapstructure:"level" deprecated:"log_level"`
+	Servers            []deprecatedServer `mapstructure:"servers"`
+	DeprecatedEmbedded `mapstructure:",squash"`
+}
+
+func TestDecodeDeprecated(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     map[string]any
+		want     deprecatedConfig
+		wantErrs []FieldError
+		lines    KeyLines
+		wantLog  string
+	}{
+		{
+			name: "current keys",
+			data: map[string]any{"level": "info", "timeout": 1},
+			want: deprecatedConfig{Level: "info", DeprecatedEmbedded: DeprecatedEmbedded{Timeout: 1}},
+		},
+		{
+			name: "deprecated keys",
+			data: map[string]any{
+				"log_level": "info",
+				"wait":      1,
+				"servers":   []any{map[string]any{"hostname": "localhost"}},
+			},
+			want: deprecatedConfig{
+				Level:              "info",
+				Servers:            []deprecatedServer{{Address: "localhost"}},
+				DeprecatedEmbedded: DeprecatedEmbedded{Timeout: 1},
+			},
+			wantLog: `level=WARN msg="deprecated config key" source=config.toml key=log_level replacement=level
+level=WARN msg="deprecated config key" source=config.toml key=servers[0].hostname replacement=servers[0].address
+level=WARN msg="deprecated config key" source=config.toml key=wait replacement=timeout
+`,
+		},
+		{
+			name:  "case-insensitive keys",
+			data:  map[string]any{"Log_Level": "info", "Servers": []any{map[string]any{"HOST": "localhost"}}},
+			want:  deprecatedConfig{Level: "info", Servers: []deprecatedServer{{Address: "localhost"}}},
+			lines: keyLines(map[string]int{"Log_Level": 1, "Servers.0.HOST": 3}),
+			wantLog: `level=WARN msg="deprecated config key" source=config.toml line=1 key=Log_Level replacement=level
+level=WARN msg="deprecated config key" source=config.toml line=3 key=Servers[0].HOST replacement=Servers[0].address
+`,
+		},
+		{
+			name: "conflict",
+			data: map[string]any{"log_level": "info", "level": "debug"},
+			wantErrs: []FieldError{
+				{Path: "log_level", Cause: fmt.Errorf("deprecated key conflicts with level")},
+			},
+		},
+		{
+			name: "case-insensitive conflict",
+			data: map[string]any{"log_level": "info", "Level": "debug"},
+			wantErrs: []FieldError{
+				{Path: "log_level", Cause: fmt.Errorf("deprecated key conflicts with Level")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+					if attr.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return attr
+				},
+			})))
+
+			var got deprecatedConfig
+			data := deepCopy(tt.data)
+			opt := NewDecodeOption(WithSource("config.toml"))
+			if tt.lines != nil {
+				opt.Lines = tt.lines.Line
+			}
+			err := Decode(data, &got, opt)
+
+			var wantErr error
+			if tt.wantErrs != nil {
+				wantErr = DecodeError{Errors: tt.wantErrs}
+			}
+			if diff, ok := helper.Equal(err, wantErr, errorComparer); !ok {
+				t.Error(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+			if diff, ok := helper.Equal(buf.String(), tt.wantLog); !ok {
+				t.Error(helper.Message(t, "unexpected warnings", diff))
+			}
+			if diff, ok := helper.Equal(data, tt.data); !ok {
+				t.Error(helper.Message(t, "document modified", diff))
+			}
+		})
+	}
+}
+
+type deprecatedPointerConfig struct {
+	Level               string `mapstructure:"level" deprecated:"log_level"`
+	*DeprecatedEmbedded `mapstructure:",squash"`
+}
+
+func TestDecodeDeprecatedSquashedPointer(t *testing.T) {
+	data := map[string]any{"log_level": "info", "wait": 1}
+
+	got := deprecatedPointerConfig{DeprecatedEmbedded: &DeprecatedEmbedded{}}
+	if err := Decode(data, &got, NewDecodeOption()); err != nil {
+		t.Fatal(err)
+	}
+	want := deprecatedPointerConfig{Level: "info", DeprecatedEmbedded: &DeprecatedEmbedded{Timeout: 1}}
+	if diff, ok := helper.Equal(got, want); !ok {
+		t.Error(helper.Message(t, "unexpected result", diff))
+	}
+
+	// A nil pointer can't be squashed, which is reported by mapstructure.
+	if err := Decode(data, &deprecatedPointerConfig{}, NewDecodeOption()); err == nil {
+		t.Error(helper.Message(t, "nil squashed pointer decoded"))
+	}
+}
+
+func deepCopy(data any) any {
+	switch data := data.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(data))
+		for key, value := range data {
+			copied[key] = deepCopy(value)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(data))
+		for i, value := range data {
+			copied[i] = deepCopy(value)
+		}
+		return copied
+	}
+	return data
+}
+
+// keyLines returns the lines of the keys by their paths joined with dots.
+func keyLines(paths map[string]int) KeyLines {
+	lines := make(KeyLines)
+	for path, line := range paths {
+		lines.Set(strings.Split(path, "."), line)
+	}
+	return lines
+}
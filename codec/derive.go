@@ -0,0 +1,50 @@
+package codec
+
+import "reflect"
+
+// embedded reports whether the field is an unexported embedded struct, whose exported fields
+// are promoted by the codecs but which a derived struct type can't embed.
+func embedded(field reflect.StructField) bool {
+	return field.Anonymous && !field.IsExported() && field.Type.Kind() == reflect.Struct
+}
+
+// exportedFields returns the exported fields of the struct value with their values, where the
+// ones of the unexported embedded structs are promoted.
+func exportedFields(value reflect.Value) ([]reflect.StructField, []reflect.Value) {
+	var fields []reflect.StructField
+	var values []reflect.Value
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		switch {
+		case embedded(field):
+			promoted, promotedValues := exportedFields(value.Field(i))
+			fields = append(fields, promoted...)
+			values = append(values, promotedValues...)
+		case field.IsExported():
+			fields = append(fields, field)
+			values = append(values, value.Field(i))
+		}
+	}
+	return fields, values
+}
+
+// deriveStruct returns a value of a struct type made of the fields, holding the values. The
+// fields may be taken from other struct types.
+func deriveStruct(fields []reflect.StructField, values []reflect.Value) reflect.Value {
+	for i := range fields {
+		fields[i].Index = nil
+		fields[i].Offset = 0
+		// reflect.StructOf can't embed types with methods after the first field.
+		typ := fields[i].Type
+		if fields[i].Anonymous && i > 0 && (typ.NumMethod() > 0 || reflect.PointerTo(typ).NumMethod() > 0) {
+			fields[i].Anonymous = false
+		}
+	}
+
+	result := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, value := range values {
+		result.Field(i).Set(value)
+	}
+	return result
+}
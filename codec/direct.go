@@ -0,0 +1,118 @@
+package codec
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type directKey struct {
+	typ  reflect.Type
+	tags string
+}
+
+// directTypes caches the results of directType.
+var directTypes sync.Map
+
+// Direct reports whether a document can be decoded by a codec directly into the value pointed to
+// by result, skipping the document tree, with the same result as Decode. It is the case when no
+// option needs the document tree, the codec sees the same fields as mapstructure in the type of
+// the value, and the value is zero. The codecs decode into a new value, which is only stored on
+// success, so that nothing is left in the value when they fall back to the document tree, and
+// they would drop the fields set in a value which is not zero, where mapstructure keeps them.
+// Since mapstructure decodes into the value held by an interface, only an empty interface is
+// decoded directly, and never an interface in a struct. Neither are byte slices, which the codecs
+// decode from strings, nor arrays, which they truncate where mapstructure fails.
+//
+// tags are the struct tags naming the fields for the codec, in order of precedence, and custom
+// are the types which the codec decodes in its own way, or the interfaces it calls instead of
+// decoding a value, e.g. encoding.TextUnmarshaler.
+func (opt DecodeOption) Direct(result any, tags []string, custom ...reflect.Type) bool {
+	if opt.Tree || len(opt.Hooks) > 0 || opt.Limits.MaxDepth > 0 || opt.Limits.MaxCollection > 0 {
+		return false
+	}
+	if _, ok := opt.profile(); ok {
+		return false
+	}
+
+	value := reflect.ValueOf(result)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return false
+	}
+	if value.Elem().Kind() == reflect.Interface {
+		return value.Elem().IsNil()
+	}
+	if !value.Elem().IsZero() {
+		return false
+	}
+
+	typ := value.Elem().Type()
+	key := directKey{typ: typ, tags: strings.Join(tags, ",")}
+	if cached, ok := directTypes.Load(key); ok {
+		return cached.(bool)
+	}
+	direct := directType(typ, tags, custom, map[reflect.Type]bool{})
+	directTypes.Store(key, direct)
+	return direct
+}
+
+func directType(typ reflect.Type, tags []string, custom []reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[typ] {
+		return true
+	}
+	visited[typ] = true
+
+	for _, c := range custom {
+		if c.Kind() != reflect.Interface && typ == c {
+			return false
+		}
+		if c.Kind() == reflect.Interface && (typ.Implements(c) || reflect.PointerTo(typ).Implements(c)) {
+			return false
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice:
+		return typ.Elem().Kind() != reflect.Uint8 && directType(typ.Elem(), tags, custom, visited)
+	case reflect.Pointer:
+		return directType(typ.Elem(), tags, custom, visited)
+	case reflect.Map:
+		return typ.Key().Kind() == reflect.String && directType(typ.Elem(), tags, custom, visited)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Anonymous || field.Tag.Get("deprecated") != "" {
+				return false
+			}
+
+			name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if strings.Contains(opts, "squash") || strings.Contains(opts, "remain") {
+				return false
+			}
+			if name == "" {
+				name = field.Name
+			}
+			var codecName string
+			for _, tag := range tags {
+				if codecName, _, _ = strings.Cut(field.Tag.Get(tag), ","); codecName != "" {
+					break
+				}
+			}
+			if codecName == "" {
+				codecName = field.Name
+			}
+			if (name == "-") != (codecName == "-") || !strings.EqualFold(name, codecName) {
+				return false
+			}
+			if name != "-" && !directType(field.Type, tags, custom, visited) {
+				return false
+			}
+		}
+	case reflect.Array, reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"encoding"
+	"reflect"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type directServer struct {
+	Host string `json:"host" mapstructure:"host"`
+}
+
+type directConfig struct {
+	Level   string                  `json:"level" mapstructure:"level"`
+	Servers []directServer          `json:"servers" mapstructure:"servers"`
+	Labels  map[string]directServer `json:"labels" mapstructure:"labels"`
+	Skipped func()                  `json:"-" mapstructure:"-"`
+}
+
+type directRenamed struct {
+	Level string `json:"log_level" mapstructure:"level"`
+}
+
+type directSquashed struct {
+	Server directServer `json:"server" mapstructure:",squash"`
+}
+
+type directTime struct {
+	Time time.Time `json:"time" mapstructure:"time"`
+}
+
+type directBytes struct {
+	Data []byte `json:"data" mapstructure:"data"`
+}
+
+type directArray struct {
+	Ports [2]int `json:"ports" mapstructure:"ports"`
+}
+
+type directInterface struct {
+	Value any `json:"value" mapstructure:"value"`
+}
+
+func TestDirect(t *testing.T) {
+	custom := []reflect.Type{reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()}
+	tests := []struct {
+		name   string
+		opt    DecodeOption
+		result any
+		want   bool
+	}{
+		{name: "same fields", result: &directConfig{}, want: true},
+		{name: "value set", result: &directConfig{Level: "info"}},
+		{name: "empty interface", result: new(any), want: true},
+		{name: "interface holding a value", result: func() *any { var v any = directServer{}; return &v }()},
+		{name: "hooks", opt: NewDecodeOption(WithHooks(func(data any) any { return data })), result: &directConfig{}},
+		{name: "tree", opt: DecodeOption{Tree: true}, result: &directConfig{}},
+		{name: "profile", opt: NewDecodeOption(WithProfile("prod")), result: &directConfig{}},
+		{name: "depth limit", opt: NewDecodeOption(WithLimits(Limits{MaxDepth: 1})), result: &directConfig{}},
+		{name: "renamed field", result: &directRenamed{}},
+		{name: "squashed field", result: &directSquashed{}},
+		{name: "text unmarshaler", result: &directTime{}},
+		{name: "interface field", result: &directInterface{}},
+		{name: "byte slice", result: &directBytes{}},
+		{name: "array", result: &directArray{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.opt.Direct(tt.result, []string{"json"}, custom...)
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+		})
+	}
+}
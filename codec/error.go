@@ -0,0 +1,188 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// FieldError holds an error related to a single field of the decoded value.
+type FieldError struct {
+	// Path is the location of the field, e.g. servers[0].port. It is empty for the root value.
+	Path string
+	// Expected is the type of the field, if known.
+	Expected string
+	// Actual is the type or kind of the decoded value, if known.
+	Actual string
+	// Cause is the underlying error, e.g. the one returned by a decode hook.
+	Cause error
+}
+
+// Error returns the error in string format.
+func (e FieldError) Error() string {
+	var sb strings.Builder
+	if e.Path != "" {
+		fmt.Fprintf(&sb, "%s: ", e.Path)
+	}
+	switch {
+	case e.Expected != "" && e.Actual != "":
+		fmt.Fprintf(&sb, "expected %s, got %s", e.Expected, e.Actual)
+	case e.Expected != "":
+		fmt.Fprintf(&sb, "expected %s", e.Expected)
+	}
+	if e.Cause != nil {
+		if e.Expected != "" {
+			sb.WriteString(": ")
+		}
+		sb.WriteString(e.Cause.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the cause of the error.
+func (e FieldError) Unwrap() error {
+	return e.Cause
+}
+
+// DecodeError holds all the errors found while decoding a value.
+type DecodeError struct {
+	Errors []FieldError
+}
+
+// Error returns the error in string format.
+func (e DecodeError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("decode::%s", strings.Join(messages, "; "))
+}
+
+// Unwrap returns the errors of all fields.
+func (e DecodeError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// fieldErrorPatterns parses the messages of mapstructure, which only reports errors as strings.
+var fieldErrorPatterns = []struct {
+	pattern *regexp.Regexp
+	parse   func(match []string) FieldError
+}{
+	{
+		pattern: regexp.MustCompile(`^'(.*?)' expected type '(.*)', got unconvertible type '(.*)', value: '(.*)'$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: match[2], Actual: match[3], Cause: fmt.Errorf("unconvertible value %q", match[4])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^'(.*?)' expected type '(.*)', got '(.*)'$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: match[2], Actual: match[3]}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^cannot parse '(.*?)' as (\w+): (.*)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: match[2], Actual: "string", Cause: errors.New(match[3])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^cannot parse '(.*?)', (.*) overflows uint$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: "uint", Cause: fmt.Errorf("%s overflows uint", match[2])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^error decoding json.Number into (.*?): (.*)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Actual: "json.Number", Cause: errors.New(match[2])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^error decoding '(.*?)': (.*)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Cause: errors.New(match[2])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^'(.*?)' expected a map, got '(.*)'$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: "map", Actual: match[2]}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^'(.*?)': source data must be an array or slice, got (.*)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: "slice", Actual: match[2]}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^'(.*?)': expected source data to have length less or equal to (\d+), got (\d+)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: "array", Cause: fmt.Errorf("length %s exceeds %s", match[3], match[2])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^'(.*?)' needs a map with string keys, has '(.*)' keys$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: "map with string keys", Actual: fmt.Sprintf("map with %s keys", match[2])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^'(.*?)' has (invalid keys|unset fields): (.*)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Cause: fmt.Errorf("%s: %s", match[2], match[3])}
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^(.*?): unsupported type: (.*)$`),
+		parse: func(match []string) FieldError {
+			return FieldError{Path: match[1], Expected: match[2], Cause: errors.New("unsupported type")}
+		},
+	},
+}
+
+// newDecodeError converts an error returned by mapstructure into a DecodeError. The errors
+// returned by the hooks are kept as the causes of the field errors.
+func newDecodeError(err error, hookErrs []error) DecodeError {
+	var messages []string
+	var mapstructureErr *mapstructure.Error
+	if errors.As(err, &mapstructureErr) {
+		messages = mapstructureErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	decodeErr := DecodeError{Errors: make([]FieldError, 0, len(messages))}
+	for _, message := range messages {
+		fieldErr := FieldError{Cause: errors.New(message)}
+		for _, p := range fieldErrorPatterns {
+			if match := p.pattern.FindStringSubmatch(message); match != nil {
+				fieldErr = p.parse(match)
+				break
+			}
+		}
+		if fieldErr.Cause != nil {
+			for _, hookErr := range hookErrs {
+				if hookErr.Error() == fieldErr.Cause.Error() {
+					fieldErr.Cause = hookErr
+					break
+				}
+			}
+		}
+		decodeErr.Errors = append(decodeErr.Errors, fieldErr)
+	}
+	// mapstructure walks maps in random order.
+	sort.SliceStable(decodeErr.Errors, func(i, j int) bool {
+		return decodeErr.Errors[i].Path < decodeErr.Errors[j].Path
+	})
+	return decodeErr
+}
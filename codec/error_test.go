@@ -0,0 +1,90 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+var (
+	errHook = errors.New("hook error")
+
+	errorComparer = cmp.Comparer(func(x, y error) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.Error() == y.Error()
+	})
+)
+
+type decodeErrorServer struct {
+	Host string `mapstructure:"host"`
+	Port uint16 `mapstructure:"port"`
+}
+
+type decodeErrorConfig struct {
+	Level   string              `mapstructure:"level"`
+	Verbose bool                `mapstructure:"verbose"`
+	Servers []decodeErrorServer `mapstructure:"servers"`
+	Labels  map[string]string   `mapstructure:"labels"`
+	Secret  string              `mapstructure:"secret"`
+}
+
+func TestDecodeError(t *testing.T) {
+	hook := func(from reflect.Type, to reflect.Type, data any) (any, error) {
+		if data == "forbidden" {
+			return nil, errHook
+		}
+		return data, nil
+	}
+
+	tests := []struct {
+		name string
+		data any
+		want []FieldError
+	}{
+		{
+			name: "happy path",
+			data: map[string]any{"level": "info", "servers": []any{map[string]any{"host": "localhost", "port": 80}}},
+		},
+		{
+			name: "all errors",
+			data: map[string]any{
+				"level":   []any{"info"},
+				"verbose": "yes",
+				"servers": []any{map[string]any{"host": "localhost", "port": -1}, "localhost"},
+				"labels":  "none",
+				"secret":  "forbidden",
+			},
+			want: []FieldError{
+				{Path: "labels", Expected: "map", Actual: "string"},
+				{Path: "level", Expected: "string", Actual: "[]interface {}", Cause: errors.New(`unconvertible value "[info]"`)},
+				{Path: "secret", Cause: errHook},
+				{Path: "servers[0].port", Expected: "uint", Cause: errors.New("-1 overflows uint")},
+				{Path: "servers[1]", Expected: "map", Actual: "string"},
+				{Path: "verbose", Expected: "bool", Actual: "string", Cause: errors.New(`unconvertible value "yes"`)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result decodeErrorConfig
+			err := Decode(tt.data, &result, NewDecodeOption(WithHooks(hook)))
+
+			var decodeErr DecodeError
+			if got, want := errors.As(err, &decodeErr), tt.want != nil; got != want {
+				t.Fatal(helper.Message(t, "unexpected error", fmt.Sprintf("Err: %v", err)))
+			}
+			if diff, ok := helper.Equal(decodeErr.Errors, tt.want, errorComparer); !ok {
+				t.Error(helper.Message(t, "unexpected field errors", diff))
+			}
+			if tt.want != nil && !errors.Is(err, errHook) {
+				t.Error(helper.Message(t, "hook error is not wrapped"))
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// BackupSuffix is appended to the path of a file to name its backup.
+const BackupSuffix = ".bak"
+
+// FileOption is a type for the options of WriteFile.
+type FileOption struct {
+	// Backup keeps a copy of the previous content of the file, see BackupSuffix.
+	Backup bool
+	// Perm is the permission of a new file. It defaults to 0644, while an existing file keeps its
+	// mode and ownership.
+	Perm fs.FileMode
+}
+
+// WriteFile atomically replaces the file at path with the content written by write. The content
+// is written to a temporary file in the same directory, synced and renamed over the file, so that
+// the file is either left as it was or fully written, even if the process crashes. A symbolic
+// link at path is kept and its target replaced.
+func WriteFile(fsys afero.Fs, path string, opt FileOption, write func(io.Writer) error) (err error) {
+	path, err = resolve(fsys, path)
+	if err != nil {
+		return err
+	}
+	info, err := fsys.Stat(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := afero.TempFile(fsys, dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = fsys.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if exists {
+		if err := fsys.Chmod(tmp.Name(), info.Mode()); err != nil {
+			return err
+		}
+		if err := chown(fsys, tmp.Name(), info); err != nil {
+			return err
+		}
+		if opt.Backup {
+			if err := backup(fsys, path, info); err != nil {
+				return err
+			}
+		}
+	} else {
+		perm := opt.Perm
+		if perm == 0 {
+			perm = 0o644
+		}
+		if err := fsys.Chmod(tmp.Name(), perm); err != nil {
+			return err
+		}
+	}
+
+	if err := fsys.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(fsys, dir)
+}
+
+// resolve returns the path of the file which path links to, or path if it doesn't exist yet.
+func resolve(fsys afero.Fs, path string) (string, error) {
+	if _, ok := fsys.(*afero.OsFs); !ok {
+		return path, nil
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return path, nil
+	}
+	return resolved, err
+}
+
+// backup copies the file at path to its backup with the same mode.
+func backup(fsys afero.Fs, path string, info fs.FileInfo) error {
+	src, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := fsys.OpenFile(path+BackupSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return fsys.Chmod(path+BackupSuffix, info.Mode())
+}
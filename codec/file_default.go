@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package codec
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/spf13/afero"
+)
+
+// chown sets the ownership of the file to the one described by info, if it differs from the one
+// of the files created by the process. The file keeps the group of the process if the process
+// owns the file but isn't allowed to set its group.
+func chown(fsys afero.Fs, name string, info fs.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) == os.Geteuid() && int(stat.Gid) == os.Getegid() {
+		return nil
+	}
+	err := fsys.Chown(name, int(stat.Uid), int(stat.Gid))
+	if errors.Is(err, fs.ErrPermission) && int(stat.Uid) == os.Geteuid() {
+		return nil
+	}
+	return err
+}
+
+// syncDir syncs the directory to persist a rename in it.
+func syncDir(fsys afero.Fs, dir string) error {
+	if _, ok := fsys.(*afero.OsFs); !ok {
+		return nil
+	}
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
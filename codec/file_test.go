@@ -0,0 +1,192 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+	"github.com/spf13/afero"
+)
+
+var errWrite = errors.New("write error")
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []byte
+		mode     fs.FileMode
+		opt      FileOption
+		write    string
+		writeErr error
+		want     map[string]string
+		wantMode fs.FileMode
+	}{
+		{
+			name:     "new file",
+			write:    "new",
+			want:     map[string]string{"config.json": "new"},
+			wantMode: 0o644,
+		},
+		{
+			name:     "new file with permission",
+			opt:      FileOption{Perm: 0o600},
+			write:    "new",
+			want:     map[string]string{"config.json": "new"},
+			wantMode: 0o600,
+		},
+		{
+			name:     "existing file",
+			existing: []byte("old"),
+			mode:     0o640,
+			opt:      FileOption{Perm: 0o600},
+			write:    "new",
+			want:     map[string]string{"config.json": "new"},
+			wantMode: 0o640,
+		},
+		{
+			name:     "backup",
+			existing: []byte("old"),
+			mode:     0o640,
+			opt:      FileOption{Backup: true},
+			write:    "new",
+			want:     map[string]string{"config.json": "new", "config.json.bak": "old"},
+			wantMode: 0o640,
+		},
+		{
+			name:     "failed write",
+			existing: []byte("old"),
+			mode:     0o640,
+			opt:      FileOption{Backup: true},
+			write:    "partial",
+			writeErr: errWrite,
+			want:     map[string]string{"config.json": "old"},
+			wantMode: 0o640,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := afero.NewMemMapFs()
+			path := filepath.Join("etc", "config.json")
+			if err := fsys.MkdirAll("etc", 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing != nil {
+				if err := afero.WriteFile(fsys, path, tt.existing, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := WriteFile(fsys, path, tt.opt, func(w io.Writer) error {
+				if _, err := io.WriteString(w, tt.write); err != nil {
+					return err
+				}
+				return tt.writeErr
+			})
+			if diff, ok := helper.Equal(err, tt.writeErr, errorComparer); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+
+			got := map[string]string{}
+			entries, err := afero.ReadDir(fsys, "etc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				data, err := afero.ReadFile(fsys, filepath.Join("etc", entry.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[entry.Name()] = string(data)
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected files", diff))
+			}
+			info, err := fsys.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff, ok := helper.Equal(info.Mode().Perm(), tt.wantMode); !ok {
+				t.Error(helper.Message(t, "unexpected mode", diff))
+			}
+		})
+	}
+}
+
+func TestWriteFileOs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := WriteFile(afero.NewOsFs(), path, FileOption{Backup: true}, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if diff, ok := helper.Equal(err, error(nil)); !ok {
+		t.Fatal(helper.Message(t, "unexpected error", diff))
+	}
+
+	for name, want := range map[string]string{path: "new", path + BackupSuffix: "old"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(string(data), want); !ok {
+			t.Error(helper.Message(t, "unexpected content", diff))
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(info.Mode().Perm(), fs.FileMode(0o600)); !ok {
+			t.Error(helper.Message(t, "unexpected mode", diff))
+		}
+	}
+}
+
+func TestWriteFileSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+
+	dir := t.TempDir()
+	target, link := filepath.Join(dir, "config.json"), filepath.Join(dir, "link.json")
+	if err := os.WriteFile(target, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("config.json", link); err != nil {
+		t.Fatal(err)
+	}
+	err := WriteFile(afero.NewOsFs(), link, FileOption{Backup: true}, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(info.Mode()&fs.ModeSymlink != 0, true); !ok {
+		t.Error(helper.Message(t, "symbolic link replaced", diff))
+	}
+	for name, want := range map[string]string{target: "new", target + BackupSuffix: "old"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(string(data), want); !ok {
+			t.Error(helper.Message(t, "unexpected content", diff))
+		}
+	}
+}
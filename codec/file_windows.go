@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package codec
+
+import (
+	"io/fs"
+
+	"github.com/spf13/afero"
+)
+
+// chown does nothing since Windows has no ownership in file modes.
+func chown(fsys afero.Fs, name string, info fs.FileInfo) error {
+	return nil
+}
+
+// syncDir does nothing since Windows can't sync a directory.
+func syncDir(fsys afero.Fs, dir string) error {
+	return nil
+}
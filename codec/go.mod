@@ -0,0 +1,18 @@
+module github.com/shangkuei/gap/codec
+
+go 1.22
+
+require (
+	github.com/google/go-cmp v0.6.0
+	github.com/mitchellh/mapstructure v1.5.0
+	github.com/shangkuei/gap/testhelper v0.0.1
+)
+
+require github.com/creasty/defaults v1.7.0
+
+require (
+	github.com/spf13/afero v1.11.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/shangkuei/gap/testhelper => ../testhelper
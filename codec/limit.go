@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Limits bounds the resources used to decode untrusted documents. A zero value means unlimited.
+type Limits struct {
+	// MaxBytes is the maximum size of the document.
+	MaxBytes int64
+	// MaxDepth is the maximum nesting depth of maps and slices.
+	MaxDepth int
+	// MaxCollection is the maximum number of entries in a single map or slice.
+	MaxCollection int
+	// MaxAliases is the maximum number of alias expansions, only YAML supports aliases.
+	MaxAliases int
+}
+
+// LimitError holds an error related to a document exceeding one of its Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded limit: bytes, depth, collection or aliases.
+	Limit string
+	// Max is the value of the exceeded limit.
+	Max int64
+	// Path is the location where the limit is exceeded, if known.
+	Path string
+}
+
+// Error returns the error in string format.
+func (e LimitError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("limit::%s: %s exceeds %d", e.Path, e.Limit, e.Max)
+	}
+	return fmt.Sprintf("limit::%s exceeds %d", e.Limit, e.Max)
+}
+
+// ReadAll reads the whole document from the reader, up to MaxBytes.
+func (l Limits) ReadAll(reader io.Reader) ([]byte, error) {
+	if l.MaxBytes <= 0 {
+		return io.ReadAll(reader)
+	}
+
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(reader, l.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > l.MaxBytes {
+		return nil, LimitError{Limit: "bytes", Max: l.MaxBytes}
+	}
+	return buf.Bytes(), nil
+}
+
+// Check checks the depth and the size of the collections of the decoded document tree.
+func (l Limits) Check(data any) error {
+	if l.MaxDepth <= 0 && l.MaxCollection <= 0 {
+		return nil
+	}
+	return l.check(data, "", 0)
+}
+
+func (l Limits) check(data any, path string, depth int) error {
+	var size int
+	switch data := data.(type) {
+	case map[string]any:
+		size = len(data)
+	case map[any]any:
+		size = len(data)
+	case []any:
+		size = len(data)
+	default:
+		return nil
+	}
+
+	if depth++; l.MaxDepth > 0 && depth > l.MaxDepth {
+		return LimitError{Limit: "depth", Max: int64(l.MaxDepth), Path: path}
+	}
+	if l.MaxCollection > 0 && size > l.MaxCollection {
+		return LimitError{Limit: "collection", Max: int64(l.MaxCollection), Path: path}
+	}
+
+	switch data := data.(type) {
+	case map[string]any:
+		for key, value := range data {
+			if err := l.check(value, joinPath(path, key), depth); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		for key, value := range data {
+			if err := l.check(value, joinPath(path, fmt.Sprint(key)), depth); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, value := range data {
+			if err := l.check(value, path+"["+strconv.Itoa(i)+"]", depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// joinPath joins the path of a map and a key in the same way as mapstructure names fields.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,55 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		raw    string
+		data   any
+		want   error
+	}{
+		{
+			name:   "unlimited",
+			raw:    "data",
+			data:   []any{[]any{[]any{1, 2, 3}}},
+			limits: Limits{},
+		},
+		{
+			name:   "bytes",
+			raw:    "data",
+			limits: Limits{MaxBytes: 3},
+			want:   LimitError{Limit: "bytes", Max: 3},
+		},
+		{
+			name:   "depth",
+			data:   map[string]any{"a": []any{1, []any{}}},
+			limits: Limits{MaxDepth: 2},
+			want:   LimitError{Limit: "depth", Max: 2, Path: "a[1]"},
+		},
+		{
+			name:   "collection",
+			data:   map[string]any{"a": map[string]any{"b": 1, "c": 2}},
+			limits: Limits{MaxCollection: 1},
+			want:   LimitError{Limit: "collection", Max: 1, Path: "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.limits.ReadAll(strings.NewReader(tt.raw))
+			if err == nil {
+				err = tt.limits.Check(tt.data)
+			}
+			if diff, ok := helper.Equal(err, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected error", diff, fmt.Sprintf("Err: %v", err)))
+			}
+		})
+	}
+}
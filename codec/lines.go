@@ -0,0 +1,40 @@
+package codec
+
+import (
+	"strings"
+	"sync"
+)
+
+// KeyLines holds the lines of the keys of a document by the keys and indices of their paths from
+// the root, see DecodeOption.Lines.
+type KeyLines map[string]int
+
+// Set records the line of the key at the path, keeping the first line of a key set several times.
+func (l KeyLines) Set(keys []string, line int) {
+	key := strings.Join(keys, "\x00")
+	if _, ok := l[key]; !ok {
+		l[key] = line
+	}
+}
+
+// Line returns the line of the key at the path, or 0 if it is unknown.
+func (l KeyLines) Line(keys []string) int {
+	return l[strings.Join(keys, "\x00")]
+}
+
+// Locate sets Lines, if it is not set, to look up the lines of the keys recorded by locate, which
+// is only called on the first lookup. locate may leave the lines incomplete, e.g. on a syntax
+// error.
+func (opt *DecodeOption) Locate(locate func(lines KeyLines)) {
+	if opt.Lines != nil {
+		return
+	}
+	lines := sync.OnceValue(func() KeyLines {
+		lines := make(KeyLines)
+		locate(lines)
+		return lines
+	})
+	opt.Lines = func(keys []string) int {
+		return lines().Line(keys)
+	}
+}
@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+
+	"github.com/creasty/defaults"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// OmitDefaults returns a copy of data without the struct fields equal to their defaults, set by
+// the `default` tags with creasty/defaults, so that a document only holds what differs from the
+// defaults. The copy is of a derived type made of the remaining fields with their tags. The
+// fields are compared recursively through nested and squashed structs, while other values, e.g.
+// slices, maps and interfaces, are compared as a whole.
+func OmitDefaults(data any) (any, error) {
+	value := reflect.ValueOf(data)
+	if !value.IsValid() {
+		return data, nil
+	}
+	omitted, err := omitDefaults(value, reflect.Value{})
+	if err != nil {
+		return nil, err
+	}
+	return omitted.Interface(), nil
+}
+
+// omitDefaults copies the struct value, or pointer to a struct, without the fields equal to the
+// ones of the default value. The defaults of the type are used if the default value is invalid.
+func omitDefaults(value reflect.Value, defaultValue reflect.Value) (reflect.Value, error) {
+	if !omittable(value.Type()) {
+		return value, nil
+	}
+
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return value, nil
+		}
+		if defaultValue.IsValid() && !defaultValue.IsNil() {
+			defaultValue = defaultValue.Elem()
+		} else {
+			defaultValue = reflect.Value{}
+		}
+		elem, err := omitDefaults(value.Elem(), defaultValue)
+		if err != nil {
+			return value, err
+		}
+		result := reflect.New(elem.Type())
+		result.Elem().Set(elem)
+		return result, nil
+	}
+
+	if !defaultValue.IsValid() {
+		pointer := reflect.New(value.Type())
+		if err := defaults.Set(pointer.Interface()); err != nil {
+			return value, err
+		}
+		defaultValue = pointer.Elem()
+	}
+
+	var fields []reflect.StructField
+	var values []reflect.Value
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() && !embedded(field) || field.IsExported() &&
+			reflect.DeepEqual(value.Field(i).Interface(), defaultValue.Field(i).Interface()) {
+			continue
+		}
+		fieldValue, err := omitDefaults(value.Field(i), defaultValue.Field(i))
+		if err != nil {
+			return value, err
+		}
+		if embedded(field) {
+			promoted, promotedValues := exportedFields(fieldValue)
+			fields = append(fields, promoted...)
+			values = append(values, promotedValues...)
+			continue
+		}
+		field.Type = fieldValue.Type()
+		fields = append(fields, field)
+		values = append(values, fieldValue)
+	}
+	return deriveStruct(fields, values), nil
+}
+
+// omittable reports whether the fields of the type, a struct or a pointer to a struct, can be
+// omitted. The types encoding themselves, e.g. time.Time, are kept as they are.
+func omittable(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for _, marshaler := range []reflect.Type{textMarshalerType, jsonMarshalerType} {
+		if typ.Implements(marshaler) || reflect.PointerTo(typ).Implements(marshaler) {
+			return false
+		}
+	}
+	return true
+}
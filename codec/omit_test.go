@@ -0,0 +1,92 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type OmitFile struct {
+	Path       string `json:"path,omitempty" mapstructure:"path"`
+	Permission uint32 `json:"permission" mapstructure:"permission" default:"416"`
+}
+
+type omitServer struct {
+	Host    string        `json:"host" mapstructure:"host" default:"localhost"`
+	Port    int           `json:"port" mapstructure:"port" default:"80"`
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout" default:"5s"`
+}
+
+type omitConfig struct {
+	Level    string      `json:"level" mapstructure:"level" default:"info"`
+	Verbose  bool        `json:"verbose" mapstructure:"verbose"`
+	Tags     []string    `json:"tags" mapstructure:"tags" default:"[\"a\"]"`
+	Server   omitServer  `json:"server" mapstructure:"server"`
+	Backup   *omitServer `json:"backup" mapstructure:"backup"`
+	OmitFile `mapstructure:",squash"`
+	secret   string
+}
+
+func TestOmitDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+		want string
+	}{
+		{
+			name: "defaults",
+			data: omitConfig{
+				Level:    "info",
+				Tags:     []string{"a"},
+				Server:   omitServer{Host: "localhost", Port: 80, Timeout: 5 * time.Second},
+				OmitFile: OmitFile{Permission: 416},
+				secret:   "secret",
+			},
+			want: `{}`,
+		},
+		{
+			name: "nested and squashed",
+			data: &omitConfig{
+				Level:    "debug",
+				Tags:     []string{"a", "b"},
+				Server:   omitServer{Host: "localhost", Port: 8080, Timeout: 5 * time.Second},
+				OmitFile: OmitFile{Path: "app.log", Permission: 416},
+			},
+			want: `{"level":"debug","tags":["a","b"],"server":{"port":8080},"path":"app.log"}`,
+		},
+		{
+			name: "pointer",
+			data: omitConfig{
+				Level:    "info",
+				Verbose:  true,
+				Tags:     []string{"a"},
+				Server:   omitServer{Host: "localhost", Port: 80, Timeout: 5 * time.Second},
+				Backup:   &omitServer{Host: "backup", Port: 80, Timeout: 5 * time.Second},
+				OmitFile: OmitFile{Permission: 384},
+			},
+			want: `{"verbose":true,"backup":{"host":"backup"},"permission":384}`,
+		},
+		{
+			name: "not a struct",
+			data: map[string]any{"level": "info"},
+			want: `{"level":"info"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OmitDefaults(tt.data)
+			if diff, ok := helper.Equal(err, error(nil)); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			data, err := json.Marshal(got)
+			if diff, ok := helper.Equal(err, error(nil)); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(string(data), tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected document", diff))
+			}
+		})
+	}
+}
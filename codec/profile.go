@@ -0,0 +1,86 @@
+package codec
+
+import (
+	"fmt"
+	"os"
+)
+
+// ProfilesKey is the key of the block holding the profiles in a document, e.g. [profiles.prod]
+// in TOML.
+const ProfilesKey = "profiles"
+
+// ProfileError holds an error related to the profiles block of a document.
+type ProfileError struct {
+	profile string
+	err     error
+}
+
+// Error returns the error in string format.
+func (e ProfileError) Error() string {
+	return fmt.Sprintf("profile(%s)::%s", e.profile, e.err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e ProfileError) Unwrap() error {
+	return e.err
+}
+
+// profile returns the name of the active profile and whether profiles are enabled.
+func (opt DecodeOption) profile() (string, bool) {
+	if opt.Profile != "" {
+		return opt.Profile, true
+	}
+	if opt.ProfileEnv != "" {
+		return os.Getenv(opt.ProfileEnv), true
+	}
+	return "", false
+}
+
+// applyProfile deep-merges the active profile over the document tree and removes the profiles
+// block from it.
+func applyProfile(data any, profile string) (any, error) {
+	root, ok := data.(map[string]any)
+	if !ok {
+		return data, nil
+	}
+	profiles, ok := root[ProfilesKey]
+	if !ok {
+		if profile != "" {
+			return nil, ProfileError{profile: profile, err: fmt.Errorf("no %s in document", ProfilesKey)}
+		}
+		return data, nil
+	}
+	delete(root, ProfilesKey)
+	if profile == "" {
+		return root, nil
+	}
+
+	sections, ok := profiles.(map[string]any)
+	if !ok {
+		return nil, ProfileError{profile: profile, err: fmt.Errorf("%s is not a map", ProfilesKey)}
+	}
+	section, ok := sections[profile]
+	if !ok {
+		return nil, ProfileError{profile: profile, err: fmt.Errorf("not found in %s", ProfilesKey)}
+	}
+	overlay, ok := section.(map[string]any)
+	if !ok {
+		return nil, ProfileError{profile: profile, err: fmt.Errorf("%s.%s is not a map", ProfilesKey, profile)}
+	}
+	return merge(root, overlay), nil
+}
+
+// merge merges the overlay into the base recursively. Maps are merged by key, while any other
+// value of the overlay replaces the one of the base.
+func merge(base, overlay map[string]any) map[string]any {
+	for key, value := range overlay {
+		baseMap, baseOk := base[key].(map[string]any)
+		overlayMap, overlayOk := value.(map[string]any)
+		if baseOk && overlayOk {
+			base[key] = merge(baseMap, overlayMap)
+		} else {
+			base[key] = value
+		}
+	}
+	return base
+}
@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type profileDatabase struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
+}
+
+type profileConfig struct {
+	Level    string          `mapstructure:"level"`
+	Database profileDatabase `mapstructure:"database"`
+	Tags     []string        `mapstructure:"tags"`
+}
+
+func TestDecodeProfile(t *testing.T) {
+	document := func() map[string]any {
+		return map[string]any{
+			"level":    "debug",
+			"database": map[string]any{"host": "localhost", "port": 5432},
+			"tags":     []any{"dev"},
+			"profiles": map[string]any{
+				"prod": map[string]any{
+					"level":    "info",
+					"database": map[string]any{"host": "db.example.com"},
+					"tags":     []any{"prod", "eu"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    []func(*DecodeOption)
+		env     string
+		want    profileConfig
+		wantErr bool
+	}{
+		{
+			name: "no profile",
+			opts: []func(*DecodeOption){WithProfileEnv("GAP_TEST_PROFILE")},
+			want: profileConfig{Level: "debug", Database: profileDatabase{Host: "localhost", Port: 5432}, Tags: []string{"dev"}},
+		},
+		{
+			name: "profile option",
+			opts: []func(*DecodeOption){WithProfile("prod")},
+			want: profileConfig{Level: "info", Database: profileDatabase{Host: "db.example.com", Port: 5432}, Tags: []string{"prod", "eu"}},
+		},
+		{
+			name: "profile environment variable",
+			opts: []func(*DecodeOption){WithProfileEnv("GAP_TEST_PROFILE")},
+			env:  "prod",
+			want: profileConfig{Level: "info", Database: profileDatabase{Host: "db.example.com", Port: 5432}, Tags: []string{"prod", "eu"}},
+		},
+		{
+			name:    "unknown profile",
+			opts:    []func(*DecodeOption){WithProfile("staging")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GAP_TEST_PROFILE", tt.env)
+
+			var got profileConfig
+			err := Decode(document(), &got, NewDecodeOption(tt.opts...))
+			var profileErr ProfileError
+			if errors.As(err, &profileErr) != tt.wantErr {
+				t.Error(helper.Message(t, "unexpected error", fmt.Sprintf("Err: %v", err)))
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+		})
+	}
+}
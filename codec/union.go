@@ -0,0 +1,251 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// UnionError holds an error related to the discriminator of a value decoded by a Registry.
+type UnionError struct {
+	key  string
+	name string
+}
+
+// Error returns the error in string format.
+func (e UnionError) Error() string {
+	if e.name == "" {
+		return fmt.Sprintf("union::missing discriminator %q", e.key)
+	}
+	return fmt.Sprintf("union::unknown %s %q", e.key, e.name)
+}
+
+// Union is a discriminated union of types, implemented by Registry, which the codecs encode with
+// their discriminators, see EncodeUnions.
+type Union interface {
+	// target returns the interface of the union.
+	target() reflect.Type
+	// discriminate returns the discriminator key and the name of the type, and whether the type
+	// is a registered member of the union.
+	discriminate(typ reflect.Type) (string, string, bool)
+}
+
+// Registry is a discriminated union of the concrete types of the interface I. A document holds
+// one of the types as a map, whose Key names the registered type, e.g. type = "file" in TOML.
+type Registry[I any] struct {
+	Key   string
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// NewRegistry creates an empty Registry of the interface I, discriminated by the key.
+func NewRegistry[I any](key string) *Registry[I] {
+	return &Registry[I]{
+		Key:   key,
+		types: map[string]reflect.Type{},
+		names: map[reflect.Type]string{},
+	}
+}
+
+// Register registers the type of the value under the name. The value is only used for its type,
+// e.g. &FileOutput{} decodes a new *FileOutput. It panics if the type is not a struct or a pointer
+// to a struct.
+func (r *Registry[I]) Register(name string, value I) *Registry[I] {
+	typ := reflect.TypeOf(value)
+	if typ == nil || typ.Kind() != reflect.Struct && (typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct) {
+		panic(fmt.Sprintf("union: %v is not a struct", typ))
+	}
+	r.types[name] = typ
+	r.names[typ] = name
+	return r
+}
+
+// DecodeHook returns a DecodeHookFunc setting a value of I to a new value of the type named by
+// the discriminator of the decoded map, which is then decoded into it. A value of I which
+// already holds the named type, e.g. set by defaults, is decoded into as it is.
+func (r *Registry[I]) DecodeHook() mapstructure.DecodeHookFuncValue {
+	target := r.target()
+	return func(from reflect.Value, to reflect.Value) (any, error) {
+		for from.Kind() == reflect.Interface && !from.IsNil() {
+			from = from.Elem()
+		}
+		if to.Type() != target || !to.CanSet() || from.Kind() != reflect.Map || from.Type().Key().Kind() != reflect.String {
+			return from.Interface(), nil
+		}
+
+		discriminator := from.MapIndex(reflect.ValueOf(r.Key).Convert(from.Type().Key()))
+		for discriminator.IsValid() && discriminator.Kind() == reflect.Interface {
+			discriminator = discriminator.Elem()
+		}
+		if !discriminator.IsValid() || discriminator.Kind() != reflect.String {
+			return nil, UnionError{key: r.Key}
+		}
+		name := discriminator.String()
+		typ, ok := r.types[name]
+		if !ok {
+			return nil, UnionError{key: r.Key, name: name}
+		}
+
+		if to.IsNil() || to.Elem().Type() != typ {
+			if typ.Kind() == reflect.Pointer {
+				to.Set(reflect.New(typ.Elem()))
+			} else {
+				to.Set(reflect.Zero(typ))
+			}
+		}
+		return from.Interface(), nil
+	}
+}
+
+func (r *Registry[I]) target() reflect.Type {
+	return reflect.TypeOf((*I)(nil)).Elem()
+}
+
+func (r *Registry[I]) discriminate(typ reflect.Type) (string, string, bool) {
+	name, ok := r.names[typ]
+	return r.Key, name, ok
+}
+
+var anyType = reflect.TypeOf((*any)(nil)).Elem()
+
+// EncodeUnions returns a copy of data where the values of the unions hold their discriminators,
+// so that they are decoded back by the hooks of the unions. Since the value of an interface can't
+// be of another type, the copy is of a derived type where the interfaces of the unions are
+// replaced with any, and their values with structs made of a discriminator field, tagged with
+// the key for every codec, and the fields of the values.
+func EncodeUnions(data any, unions ...Union) any {
+	value := reflect.ValueOf(data)
+	if !value.IsValid() || len(unions) == 0 {
+		return data
+	}
+	targets := map[reflect.Type]bool{}
+	for _, union := range unions {
+		targets[union.target()] = true
+	}
+	return unionValue(value, unions, targets).Interface()
+}
+
+// hasUnion reports whether the type holds an interface of the unions.
+func hasUnion(typ reflect.Type, targets map[reflect.Type]bool, visited map[reflect.Type]bool) bool {
+	if visited[typ] {
+		return false
+	}
+	visited[typ] = true
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		return typ == anyType || targets[typ]
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return hasUnion(typ.Elem(), targets, visited)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if typ.Field(i).IsExported() && hasUnion(typ.Field(i).Type, targets, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unionValue copies the value into a value of a derived type holding the discriminators of the
+// unions. The value is returned as it is if its type holds no interface of the unions.
+func unionValue(value reflect.Value, unions []Union, targets map[reflect.Type]bool) reflect.Value {
+	if !hasUnion(value.Type(), targets, map[reflect.Type]bool{}) {
+		return value
+	}
+
+	switch value.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return reflect.Zero(anyType)
+		}
+		result := reflect.New(anyType).Elem()
+		result.Set(unionMember(value.Elem(), unions, targets))
+		return result
+	case reflect.Pointer:
+		if value.IsNil() {
+			return reflect.Zero(reflect.PointerTo(anyType))
+		}
+		elem := unionValue(value.Elem(), unions, targets)
+		result := reflect.New(elem.Type())
+		result.Elem().Set(elem)
+		return result
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return reflect.Zero(reflect.SliceOf(anyType))
+		}
+		result := make([]any, value.Len())
+		for i := range result {
+			result[i] = unionValue(value.Index(i), unions, targets).Interface()
+		}
+		return reflect.ValueOf(result)
+	case reflect.Map:
+		if value.IsNil() {
+			return reflect.Zero(reflect.MapOf(value.Type().Key(), anyType))
+		}
+		result := reflect.MakeMapWithSize(reflect.MapOf(value.Type().Key(), anyType), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), unionValue(iter.Value(), unions, targets))
+		}
+		return result
+	case reflect.Struct:
+		return unionStruct(value, "", "", unions, targets)
+	}
+	return value
+}
+
+// unionMember copies the value of an interface, adding the discriminator of its union if it is
+// a member of one.
+func unionMember(value reflect.Value, unions []Union, targets map[reflect.Type]bool) reflect.Value {
+	for _, union := range unions {
+		key, name, ok := union.discriminate(value.Type())
+		if !ok {
+			continue
+		}
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return reflect.Zero(anyType)
+			}
+			value = value.Elem()
+		}
+
+		return unionStruct(value, key, name, unions, targets)
+	}
+	return unionValue(value, unions, targets)
+}
+
+// unionStruct copies the exported fields of the struct value into a value of a derived struct
+// type, led by a discriminator field holding the name if the key is not empty.
+func unionStruct(value reflect.Value, key, name string, unions []Union, targets map[reflect.Type]bool) reflect.Value {
+	typ := value.Type()
+	var fields []reflect.StructField
+	var values []reflect.Value
+	if key != "" {
+		discriminator := reflect.StructField{
+			Name: "Discriminator",
+			Type: reflect.TypeOf(name),
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:"%[1]s" yaml:"%[1]s" toml:"%[1]s" mapstructure:"%[1]s"`, key)),
+		}
+		for _, taken := typ.FieldByName(discriminator.Name); taken; _, taken = typ.FieldByName(discriminator.Name) {
+			discriminator.Name += "_"
+		}
+		fields = append(fields, discriminator)
+		values = append(values, reflect.ValueOf(name))
+	}
+	exported, exportedValues := exportedFields(value)
+	for i, field := range exported {
+		// The discriminator replaces a field of the same key, which it is decoded into.
+		if fieldName, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ","); key != "" &&
+			(strings.EqualFold(fieldName, key) || fieldName == "" && strings.EqualFold(field.Name, key)) {
+			continue
+		}
+		fieldValue := unionValue(exportedValues[i], unions, targets)
+		field.Type = fieldValue.Type()
+		fields = append(fields, field)
+		values = append(values, fieldValue)
+	}
+	return deriveStruct(fields, values)
+}
@@ -0,0 +1,145 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type UnionOutput interface {
+	Describe() string
+}
+
+type UnionFile struct {
+	Path string `json:"path" mapstructure:"path"`
+}
+
+func (f *UnionFile) Describe() string { return "file " + f.Path }
+
+type UnionConsole struct {
+	Type   string `json:"type" mapstructure:"type"`
+	Stream string `json:"stream" mapstructure:"stream"`
+}
+
+func (c UnionConsole) Describe() string { return "console " + c.Stream }
+
+type unionConfig struct {
+	Output  UnionOutput            `json:"output" mapstructure:"output"`
+	Outputs []UnionOutput          `json:"outputs" mapstructure:"outputs"`
+	Named   map[string]UnionOutput `json:"named,omitempty" mapstructure:"named"`
+	Level   string                 `json:"level" mapstructure:"level"`
+}
+
+func unionRegistry() *Registry[UnionOutput] {
+	return NewRegistry[UnionOutput]("type").
+		Register("file", &UnionFile{}).
+		Register("console", UnionConsole{})
+}
+
+func TestRegistryDecodeHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]any
+		init    unionConfig
+		want    unionConfig
+		wantErr error
+	}{
+		{
+			name: "members",
+			data: map[string]any{
+				"output": map[string]any{"type": "file", "path": "/var/log/app.log"},
+				"outputs": []any{
+					map[string]any{"type": "console", "stream": "stderr"},
+					map[string]any{"type": "file", "path": "app.log"},
+				},
+				"named": map[string]any{"audit": map[string]any{"type": "file", "path": "audit.log"}},
+				"level": "info",
+			},
+			want: unionConfig{
+				Output: &UnionFile{Path: "/var/log/app.log"},
+				Outputs: []UnionOutput{
+					UnionConsole{Type: "console", Stream: "stderr"},
+					&UnionFile{Path: "app.log"},
+				},
+				Named: map[string]UnionOutput{"audit": &UnionFile{Path: "audit.log"}},
+				Level: "info",
+			},
+		},
+		{
+			name: "decode into the default member",
+			data: map[string]any{"output": map[string]any{"type": "console"}},
+			init: unionConfig{Output: UnionConsole{Stream: "stdout"}},
+			want: unionConfig{Output: UnionConsole{Type: "console", Stream: "stdout"}},
+		},
+		{
+			name: "replace the default member",
+			data: map[string]any{"output": map[string]any{"type": "file", "path": "app.log"}},
+			init: unionConfig{Output: UnionConsole{Stream: "stdout"}},
+			want: unionConfig{Output: &UnionFile{Path: "app.log"}},
+		},
+		{
+			name: "unknown member",
+			data: map[string]any{"output": map[string]any{"type": "syslog"}},
+			wantErr: DecodeError{Errors: []FieldError{
+				{Path: "output", Cause: UnionError{key: "type", name: "syslog"}},
+			}},
+		},
+		{
+			name: "missing discriminator",
+			data: map[string]any{"outputs": []any{map[string]any{"path": "app.log"}}},
+			wantErr: DecodeError{Errors: []FieldError{
+				{Path: "outputs[0]", Cause: UnionError{key: "type"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.init
+			err := Decode(tt.data, &got, NewDecodeOption(WithHooks(unionRegistry().DecodeHook())))
+			if diff, ok := helper.Equal(err, tt.wantErr, errorComparer); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+		})
+	}
+}
+
+func TestEncodeUnions(t *testing.T) {
+	config := unionConfig{
+		Output: &UnionFile{Path: "app.log"},
+		Outputs: []UnionOutput{
+			UnionConsole{Stream: "stderr"},
+			nil,
+		},
+		Level: "info",
+	}
+
+	data, err := json.Marshal(EncodeUnions(config, unionRegistry()))
+	if diff, ok := helper.Equal(err, error(nil)); !ok {
+		t.Fatal(helper.Message(t, "unexpected error", diff))
+	}
+	want := `{"output":{"type":"file","path":"app.log"},"outputs":[{"type":"console","stream":"stderr"},null],"level":"info"}`
+	if diff, ok := helper.Equal(string(data), want); !ok {
+		t.Error(helper.Message(t, "unexpected document", diff))
+	}
+
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+	var got unionConfig
+	err = Decode(tree, &got, NewDecodeOption(WithHooks(unionRegistry().DecodeHook())))
+	if diff, ok := helper.Equal(err, error(nil)); !ok {
+		t.Fatal(helper.Message(t, "unexpected error", diff))
+	}
+	config.Outputs[0] = UnionConsole{Type: "console", Stream: "stderr"}
+	if diff, ok := helper.Equal(got, config); !ok {
+		t.Error(helper.Message(t, "unexpected round trip", diff))
+	}
+}
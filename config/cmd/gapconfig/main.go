@@ -0,0 +1,209 @@
+// Command gapconfig inspects configuration documents in JSON, YAML or TOML.
+//
+// Usage:
+//
+//	gapconfig diff [-output text|json|patch] [-from-format FORMAT] [-to-format FORMAT] FROM TO
+//	gapconfig encrypt [-key-file FILE] [-match REGEXP] [-format FORMAT] [-w] FILE
+//	gapconfig decrypt [-key-file FILE] [-format FORMAT] [-w] FILE
+//	gapconfig keygen
+//
+// The diff command exits with 0 if the documents are the same, 1 if they differ and 2 on error.
+//
+// The encrypt command encrypts the string values whose keys match the pattern with the key, see
+// config.Secrets, and the decrypt command decrypts them back. The key is read from the key file,
+// or from the GAP_CONFIG_KEY environment variable, and a new one is printed by the keygen
+// command. Only the encrypted or decrypted values are rewritten, keeping the rest of the document,
+// e.g. its comments, as it is. The document is printed, or written back to the file with -w.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/shangkuei/gap/codec"
+	"github.com/shangkuei/gap/config"
+	"github.com/spf13/afero"
+)
+
+// defaultMatch is the default pattern of the keys of the values to encrypt.
+const defaultMatch = `(?i)(password|passwd|secret|token|credential|private_?key)`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command of the arguments and returns its exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		usage(stderr)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "diff":
+		err = diff(args[1:], stdout, stderr)
+	case "encrypt", "decrypt":
+		err = secret(args[0], args[1:], stdout, stderr)
+	case "keygen":
+		err = keygen(stdout)
+	case "-h", "-help", "--help", "help":
+		usage(stderr)
+		return 0
+	default:
+		err = fmt.Errorf("unknown command %q", args[0])
+	}
+
+	if err, ok := err.(exitError); ok {
+		return int(err)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "gapconfig:", err)
+		return 2
+	}
+	return 0
+}
+
+func usage(stderr io.Writer) {
+	fmt.Fprintln(stderr, `usage: gapconfig diff [-output text|json|patch] [-from-format FORMAT] [-to-format FORMAT] FROM TO
+       gapconfig encrypt [-key-file FILE] [-match REGEXP] [-format FORMAT] [-w] FILE
+       gapconfig decrypt [-key-file FILE] [-format FORMAT] [-w] FILE
+       gapconfig keygen`)
+}
+
+// exitError is returned by a command which completed but wants a non zero exit status.
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func diff(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("output", "text", "output format: text, json or patch")
+	fromFormat := flags.String("from-format", "", "format of FROM, detected from the extension if empty")
+	toFormat := flags.String("to-format", "", "format of TO, detected from the extension if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("diff expects 2 files, got %d", flags.NArg())
+	}
+
+	write, ok := map[string]func(io.Writer, []config.Change) error{
+		"text":  config.WriteText,
+		"json":  config.WriteJSON,
+		"patch": config.WriteJSONPatch,
+	}[*output]
+	if !ok {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	from, err := openDocument(flags.Arg(0), *fromFormat)
+	if err != nil {
+		return err
+	}
+	defer from.Reader.(io.Closer).Close()
+	to, err := openDocument(flags.Arg(1), *toFormat)
+	if err != nil {
+		return err
+	}
+	defer to.Reader.(io.Closer).Close()
+
+	changes, err := config.DiffDocuments(from, to)
+	if err != nil {
+		return err
+	}
+	if err := write(stdout, changes); err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return exitError(1)
+	}
+	return nil
+}
+
+func secret(command string, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	keyFile := flags.String("key-file", "", "file of the key, read from "+config.KeyEnv+" if empty")
+	format := flags.String("format", "", "format of FILE, detected from the extension if empty")
+	write := flags.Bool("w", false, "write the document back to FILE")
+	var match *string
+	if command == "encrypt" {
+		match = flags.String("match", defaultMatch, "pattern of the keys of the values to encrypt")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%s expects 1 file, got %d", command, flags.NArg())
+	}
+
+	secrets, err := config.LoadSecrets(*keyFile)
+	if err != nil {
+		return err
+	}
+	doc, err := openDocument(flags.Arg(0), *format)
+	if err != nil {
+		return err
+	}
+	raw, err := io.ReadAll(doc.Reader)
+	doc.Reader.(io.Closer).Close()
+	if err != nil {
+		return err
+	}
+
+	if command == "encrypt" {
+		pattern, err := regexp.Compile(*match)
+		if err != nil {
+			return err
+		}
+		raw, err = secrets.EncryptDocument(raw, doc.Format, pattern)
+		if err != nil {
+			return err
+		}
+	} else if raw, err = secrets.DecryptDocument(raw, doc.Format); err != nil {
+		return err
+	}
+
+	if *write {
+		return codec.WriteFile(afero.NewOsFs(), flags.Arg(0), codec.FileOption{}, func(writer io.Writer) error {
+			_, err := writer.Write(raw)
+			return err
+		})
+	}
+	_, err = stdout.Write(raw)
+	return err
+}
+
+func keygen(stdout io.Writer) error {
+	key, err := config.GenerateKey()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, key)
+	return err
+}
+
+func openDocument(path, format string) (config.Document, error) {
+	var (
+		doc config.Document
+		err error
+	)
+	if format != "" {
+		doc.Format, err = config.ParseFormat(format)
+	} else {
+		doc.Format, err = config.FormatFromPath(path)
+	}
+	if err != nil {
+		return doc, err
+	}
+
+	doc.Reader, err = os.Open(path)
+	return doc, err
+}
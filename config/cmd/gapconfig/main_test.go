@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shangkuei/gap/config"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+// writeFiles writes the files in a temporary directory and returns its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"from.json": `{"level": "info", "port": 80}`,
+		"from.txt":  `{"level": "info", "port": 80}`,
+		"to.yaml":   "level: debug\nport: 80\n",
+		"same.toml": "level = 'info'\nport = 80\n",
+	})
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "no command", wantCode: 2, wantStderr: "usage: gapconfig diff"},
+		{name: "help", args: []string{"help"}, wantStderr: "usage: gapconfig diff"},
+		{name: "unknown command", args: []string{"lint"}, wantCode: 2, wantStderr: `gapconfig: unknown command "lint"`},
+		{name: "diff same documents", args: []string{"diff", file("from.json"), file("same.toml")}},
+		{
+			name:       "diff text",
+			args:       []string{"diff", file("from.json"), file("to.yaml")},
+			wantCode:   1,
+			wantStdout: "~ level: \"info\" => \"debug\"\n",
+		},
+		{
+			name:       "diff patch",
+			args:       []string{"diff", "-output", "patch", file("from.json"), file("to.yaml")},
+			wantCode:   1,
+			wantStdout: "[\n  {\n    \"op\": \"replace\",\n    \"path\": \"/level\",\n    \"value\": \"debug\"\n  }\n]\n",
+		},
+		{
+			name:       "diff format flag",
+			args:       []string{"diff", "-from-format", "json", file("from.txt"), file("to.yaml")},
+			wantCode:   1,
+			wantStdout: "~ level: \"info\" => \"debug\"\n",
+		},
+		{name: "diff unknown extension", args: []string{"diff", file("from.txt"), file("to.yaml")}, wantCode: 2, wantStderr: "gapconfig: "},
+		{name: "diff unknown format", args: []string{"diff", "-to-format", "ini", file("from.json"), file("to.yaml")}, wantCode: 2, wantStderr: "gapconfig: "},
+		{name: "diff missing file", args: []string{"diff", file("from.json"), file("missing.json")}, wantCode: 2, wantStderr: "gapconfig: "},
+		{name: "diff one file", args: []string{"diff", file("from.json")}, wantCode: 2, wantStderr: "gapconfig: diff expects 2 files, got 1"},
+		{name: "diff unknown output", args: []string{"diff", "-output", "html", file("from.json"), file("to.yaml")}, wantCode: 2, wantStderr: `gapconfig: unknown output format "html"`},
+		{name: "diff unknown flag", args: []string{"diff", "-color", file("from.json"), file("to.yaml")}, wantCode: 2, wantStderr: "flag provided but not defined: -color"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+			if diff, ok := helper.Equal(code, tt.wantCode); !ok {
+				t.Error(helper.Message(t, "unexpected exit status", diff, stderr.String()))
+			}
+			if diff, ok := helper.Equal(stdout.String(), tt.wantStdout); !ok {
+				t.Error(helper.Message(t, "unexpected output", diff))
+			}
+			if diff, ok := helper.Equal(strings.Contains(stderr.String(), tt.wantStderr), true); !ok {
+				t.Error(helper.Message(t, "unexpected error output", diff, stderr.String()))
+			}
+		})
+	}
+}
+
+func TestRunSecrets(t *testing.T) {
+	raw := "# Database.\nhost: \"db\"\npassword: hunter2 # keep it secret\n"
+	dir := writeFiles(t, map[string]string{"config.yaml": raw})
+	path := filepath.Join(dir, "config.yaml")
+	key, err := config.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.KeyEnv, key)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"encrypt", path}, &stdout, &stderr); code != 0 {
+		t.Fatal(helper.Message(t, "encrypt failed", stderr.String()))
+	}
+	encrypted := stdout.String()
+	for value, want := range map[string]bool{"hunter2": false, `host: "db"`: true, "# keep it secret": true} {
+		if diff, ok := helper.Equal(strings.Contains(encrypted, value), want); !ok {
+			t.Error(helper.Message(t, "unexpected encrypted document", diff, value, encrypted))
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"encrypt", "-match", "host", "-w", path}, &stdout, &stderr); code != 0 {
+		t.Fatal(helper.Message(t, "encrypt -w failed", stderr.String()))
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(stdout.String(), ""); !ok {
+		t.Error(helper.Message(t, "document printed with -w", diff))
+	}
+	for value, want := range map[string]bool{`host: "db"`: false, "hunter2": true} {
+		if diff, ok := helper.Equal(strings.Contains(string(written), value), want); !ok {
+			t.Error(helper.Message(t, "unexpected written document", diff, value, string(written)))
+		}
+	}
+
+	if code := run([]string{"decrypt", path}, &stdout, &stderr); code != 0 {
+		t.Fatal(helper.Message(t, "decrypt failed", stderr.String()))
+	}
+	if diff, ok := helper.Equal(stdout.String(), raw); !ok {
+		t.Error(helper.Message(t, "unexpected decrypted document", diff))
+	}
+
+	stdout.Reset()
+	if code := run([]string{"keygen"}, &stdout, &stderr); code != 0 {
+		t.Fatal(helper.Message(t, "keygen failed", stderr.String()))
+	}
+	other := strings.TrimSpace(stdout.String())
+	t.Setenv(config.KeyEnv, other)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{name: "decrypt with another key", args: []string{"decrypt", path}, wantStderr: "gapconfig: "},
+		{name: "invalid pattern", args: []string{"encrypt", "-match", "(", path}, wantStderr: "gapconfig: "},
+		{name: "no file", args: []string{"encrypt"}, wantStderr: "gapconfig: encrypt expects 1 file, got 0"},
+		{name: "missing key file", args: []string{"decrypt", "-key-file", filepath.Join(dir, "missing"), path}, wantStderr: "gapconfig: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if diff, ok := helper.Equal(run(tt.args, &stdout, &stderr), 2); !ok {
+				t.Error(helper.Message(t, "unexpected exit status", diff))
+			}
+			if diff, ok := helper.Equal(strings.Contains(stderr.String(), tt.wantStderr), true); !ok {
+				t.Error(helper.Message(t, "unexpected error output", diff, stderr.String()))
+			}
+		})
+	}
+}
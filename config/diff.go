@@ -0,0 +1,337 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Operation is the kind of a Change.
+type Operation string
+
+const (
+	Added   Operation = "added"
+	Removed Operation = "removed"
+	Changed Operation = "changed"
+)
+
+// Path locates a value in a document. Each element is either a map key (string) or a slice
+// index (int).
+type Path []any
+
+// String returns the path in dotted notation, e.g. servers[0].host.
+func (p Path) String() string {
+	if len(p) == 0 {
+		return "."
+	}
+	var sb strings.Builder
+	for i, elem := range p {
+		switch elem := elem.(type) {
+		case int:
+			fmt.Fprintf(&sb, "[%d]", elem)
+		default:
+			key := fmt.Sprint(elem)
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			if key == "" || strings.ContainsAny(key, ".[]\"' ") {
+				key = strconv.Quote(key)
+			}
+			sb.WriteString(key)
+		}
+	}
+	return sb.String()
+}
+
+// Pointer returns the path as a JSON Pointer defined in RFC 6901.
+func (p Path) Pointer() string {
+	var sb strings.Builder
+	for _, elem := range p {
+		sb.WriteByte('/')
+		key := fmt.Sprint(elem)
+		key = strings.ReplaceAll(key, "~", "~0")
+		key = strings.ReplaceAll(key, "/", "~1")
+		sb.WriteString(key)
+	}
+	return sb.String()
+}
+
+// Change is a difference between two documents.
+type Change struct {
+	Operation Operation
+	Path      Path
+	From      any
+	To        any
+}
+
+// Document is a configuration document to be decoded with the codec of its format.
+type Document struct {
+	Reader io.Reader
+	Format Format
+}
+
+// Diff compares two decoded documents and returns the changes from one to the other. Maps are
+// compared by key regardless of order and slices are compared by index.
+func Diff(from, to any) []Change {
+	return diff(nil, normalize(reflect.ValueOf(from)), normalize(reflect.ValueOf(to)), nil)
+}
+
+// DiffDocuments decodes both documents with their codecs and compares them.
+func DiffDocuments(from, to Document) ([]Change, error) {
+	var fromData, toData any
+	if err := Decode(from.Reader, from.Format, &fromData); err != nil {
+		return nil, err
+	}
+	if err := Decode(to.Reader, to.Format, &toData); err != nil {
+		return nil, err
+	}
+	return Diff(fromData, toData), nil
+}
+
+// DiffTyped decodes both documents into S, on top of the values in its `default` tags, and
+// compares the results, so a value equal to its default is not reported as a change.
+func DiffTyped[S any](from, to Document, hooks ...mapstructure.DecodeHookFunc) ([]Change, error) {
+	decode := func(doc Document) (result S, err error) {
+		if reflect.TypeOf(result) != nil && reflect.TypeOf(result).Kind() == reflect.Struct {
+			if err := defaults.Set(&result); err != nil {
+				return result, err
+			}
+		}
+		return result, Decode(doc.Reader, doc.Format, &result, hooks...)
+	}
+
+	fromData, err := decode(from)
+	if err != nil {
+		return nil, err
+	}
+	toData, err := decode(to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(fromData, toData), nil
+}
+
+func diff(path Path, from, to any, changes []Change) []Change {
+	switch fromData := from.(type) {
+	case map[string]any:
+		toData, ok := to.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(fromData)+len(toData))
+		for key := range fromData {
+			keys = append(keys, key)
+		}
+		for key := range toData {
+			if _, ok := fromData[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fromValue, fromOk := fromData[key]
+			toValue, toOk := toData[key]
+			child := append(path[:len(path):len(path)], key)
+			switch {
+			case !toOk:
+				changes = append(changes, Change{Operation: Removed, Path: child, From: fromValue})
+			case !fromOk:
+				changes = append(changes, Change{Operation: Added, Path: child, To: toValue})
+			default:
+				changes = diff(child, fromValue, toValue, changes)
+			}
+		}
+		return changes
+	case []any:
+		toData, ok := to.([]any)
+		if !ok {
+			break
+		}
+		common := min(len(fromData), len(toData))
+		for i := 0; i < common; i++ {
+			changes = diff(append(path[:len(path):len(path)], i), fromData[i], toData[i], changes)
+		}
+		for i := common; i < len(toData); i++ {
+			changes = append(changes, Change{Operation: Added, Path: append(path[:len(path):len(path)], i), To: toData[i]})
+		}
+		// Removals are reported from the end so that applying them in order keeps indexes valid.
+		for i := len(fromData) - 1; i >= common; i-- {
+			changes = append(changes, Change{Operation: Removed, Path: append(path[:len(path):len(path)], i), From: fromData[i]})
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		changes = append(changes, Change{Operation: Changed, Path: path, From: from, To: to})
+	}
+	return changes
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// normalize converts a decoded document or a struct into a tree of map[string]any, []any and
+// scalars, so values decoded by different codecs can be compared. Struct fields are named by
+// their mapstructure tags.
+func normalize(value reflect.Value) any {
+	if !value.IsValid() {
+		return nil
+	}
+	if value.Type().Implements(textMarshalerType) && value.Kind() != reflect.Interface {
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			return nil
+		}
+		if text, err := value.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return normalize(value.Elem())
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		result := make(map[string]any, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = normalize(iter.Value())
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		result := make([]any, value.Len())
+		for i := range result {
+			result[i] = normalize(value.Index(i))
+		}
+		return result
+	case reflect.Struct:
+		result := make(map[string]any)
+		normalizeStruct(value, result)
+		return result
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt64 {
+			return value.Uint()
+		}
+		return int64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		// Integral floats are compared as integers, since JSON has no integer type.
+		if f := value.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f)
+		}
+		return value.Float()
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return value.Bool()
+	}
+	return value.Interface()
+}
+
+func normalizeStruct(value reflect.Value, result map[string]any) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "squash") || strings.Contains(opts, "remain") {
+			if squashed, ok := normalize(value.Field(i)).(map[string]any); ok {
+				for key, value := range squashed {
+					result[key] = value
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		result[name] = normalize(value.Field(i))
+	}
+}
+
+// WriteText writes the changes in a human readable format, one change per line.
+func WriteText(writer io.Writer, changes []Change) error {
+	for _, change := range changes {
+		var err error
+		switch change.Operation {
+		case Added:
+			_, err = fmt.Fprintf(writer, "+ %s: %s\n", change.Path, textValue(change.To))
+		case Removed:
+			_, err = fmt.Fprintf(writer, "- %s: %s\n", change.Path, textValue(change.From))
+		case Changed:
+			_, err = fmt.Fprintf(writer, "~ %s: %s => %s\n", change.Path, textValue(change.From), textValue(change.To))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func textValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// WriteJSON writes the changes as a JSON array of objects with the op, path, from and to keys.
+func WriteJSON(writer io.Writer, changes []Change) error {
+	result := make([]map[string]any, 0, len(changes))
+	for _, change := range changes {
+		obj := map[string]any{"op": change.Operation, "path": change.Path.String()}
+		if change.Operation != Added {
+			obj["from"] = change.From
+		}
+		if change.Operation != Removed {
+			obj["to"] = change.To
+		}
+		result = append(result, obj)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// WriteJSONPatch writes the changes as a JSON Patch defined in RFC 6902, which transforms the
+// first document into the second one.
+func WriteJSONPatch(writer io.Writer, changes []Change) error {
+	result := make([]map[string]any, 0, len(changes))
+	for _, change := range changes {
+		patch := map[string]any{"path": change.Path.Pointer()}
+		switch change.Operation {
+		case Added:
+			patch["op"], patch["value"] = "add", change.To
+		case Removed:
+			patch["op"] = "remove"
+		case Changed:
+			patch["op"], patch["value"] = "replace", change.To
+		}
+		result = append(result, patch)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
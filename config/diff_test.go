@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type diffServer struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port" default:"80"`
+}
+
+type diffConfig struct {
+	Type    string       `mapstructure:"type" default:"console"`
+	Level   string       `mapstructure:"level" default:"info"`
+	Source  bool         `mapstructure:"source" default:"true"`
+	Servers []diffServer `mapstructure:"servers"`
+}
+
+func openTestdata(t *testing.T, name string) Document {
+	t.Helper()
+
+	format, err := FormatFromPath(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Document{Reader: bytes.NewReader(data), Format: format}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from any
+		to   any
+		want []Change
+	}{
+		{
+			name: "equal numbers of different types",
+			from: map[string]any{"port": float64(80)},
+			to:   map[string]any{"port": uint64(80)},
+		},
+		{
+			name: "nested map",
+			from: map[string]any{"a": map[string]any{"b": 1, "c": 2}},
+			to:   map[string]any{"a": map[string]any{"c": 3, "d": 4}},
+			want: []Change{
+				{Operation: Removed, Path: Path{"a", "b"}, From: int64(1)},
+				{Operation: Changed, Path: Path{"a", "c"}, From: int64(2), To: int64(3)},
+				{Operation: Added, Path: Path{"a", "d"}, To: int64(4)},
+			},
+		},
+		{
+			name: "slice",
+			from: []any{"a", "b", "c"},
+			to:   []any{"a"},
+			want: []Change{
+				{Operation: Removed, Path: Path{2}, From: "c"},
+				{Operation: Removed, Path: Path{1}, From: "b"},
+			},
+		},
+		{
+			name: "type change",
+			from: map[string]any{"a": []any{"b"}},
+			to:   map[string]any{"a": "b"},
+			want: []Change{
+				{Operation: Changed, Path: Path{"a"}, From: []any{"b"}, To: "b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.from, tt.to)
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected changes", diff))
+			}
+		})
+	}
+}
+
+func TestDiffDocuments(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		typed bool
+		want  []Change
+	}{
+		{
+			name: "same document in different formats",
+			from: "base.yaml",
+			to:   "base.json",
+		},
+		{
+			name: "untyped",
+			from: "base.yaml",
+			to:   "prod.toml",
+			want: []Change{
+				{Operation: Changed, Path: Path{"level"}, From: "info", To: "warn"},
+				{Operation: Changed, Path: Path{"servers", 0, "port"}, From: int64(80), To: int64(443)},
+				{Operation: Removed, Path: Path{"servers", 1}, From: map[string]any{"host": "b.example.com", "port": int64(80)}},
+				{Operation: Added, Path: Path{"source"}, To: false},
+			},
+		},
+		{
+			name:  "typed",
+			from:  "base.yaml",
+			to:    "prod.toml",
+			typed: true,
+			want: []Change{
+				{Operation: Changed, Path: Path{"level"}, From: "info", To: "warn"},
+				{Operation: Changed, Path: Path{"servers", 0, "port"}, From: int64(80), To: int64(443)},
+				{Operation: Removed, Path: Path{"servers", 1}, From: map[string]any{"host": "b.example.com", "port": int64(80)}},
+				{Operation: Changed, Path: Path{"source"}, From: true, To: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got []Change
+				err error
+			)
+			if tt.typed {
+				got, err = DiffTyped[diffConfig](openTestdata(t, tt.from), openTestdata(t, tt.to))
+			} else {
+				got, err = DiffDocuments(openTestdata(t, tt.from), openTestdata(t, tt.to))
+			}
+			if diff, ok := helper.Equal(err, error(nil)); !ok {
+				t.Error(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected changes", diff))
+			}
+		})
+	}
+}
+
+func TestWriteChanges(t *testing.T) {
+	changes := []Change{
+		{Operation: Changed, Path: Path{"servers", 0, "port"}, From: int64(80), To: int64(443)},
+		{Operation: Removed, Path: Path{"a/b"}, From: "c"},
+		{Operation: Added, Path: Path{"source"}, To: nil},
+	}
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer, []Change) error
+		want  string
+	}{
+		{
+			name: "text",
+			write: func(buf *bytes.Buffer, changes []Change) error {
+				return WriteText(buf, changes)
+			},
+			want: `~ servers[0].port: 80 => 443
+- a/b: "c"
++ source: null
+`,
+		},
+		{
+			name: "json patch",
+			write: func(buf *bytes.Buffer, changes []Change) error {
+				return WriteJSONPatch(buf, changes)
+			},
+			want: `[
+  {
+    "op": "replace",
+    "path": "/servers/0/port",
+    "value": 443
+  },
+  {
+    "op": "remove",
+    "path": "/a~1b"
+  },
+  {
+    "op": "add",
+    "path": "/source",
+    "value": null
+  }
+]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.write(&buf, changes)
+			if diff, ok := helper.Equal(err, error(nil)); !ok {
+				t.Error(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(buf.String(), tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected output", diff))
+			}
+		})
+	}
+}
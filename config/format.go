@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/shangkuei/gap/codec"
+	"github.com/shangkuei/gap/json"
+	"github.com/shangkuei/gap/toml"
+	"github.com/shangkuei/gap/yaml"
+)
+
+// Format is the encoding format of a configuration document.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// FormatError holds an error related to an unknown format.
+type FormatError struct {
+	format string
+}
+
+// Error returns the error in string format.
+func (e FormatError) Error() string {
+	return fmt.Sprintf("config::unsupported format %q", e.format)
+}
+
+// ParseFormat parses the name of a format, e.g. "yml" or "toml".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	case "toml":
+		return TOML, nil
+	}
+	return "", FormatError{format: name}
+}
+
+// FormatFromPath detects the format from the extension of the path.
+func FormatFromPath(path string) (Format, error) {
+	return ParseFormat(filepath.Ext(path))
+}
+
+// Decode decodes data from the reader with the codec of the format and stores the result in the
+// value pointed to by result.
+func Decode[S any](reader io.Reader, format Format, result *S, hooks ...mapstructure.DecodeHookFunc) error {
+	return DecodeWith(reader, format, result, codec.WithHooks(hooks...))
+}
+
+// DecodeWith decodes data from the reader with the codec of the format and the options, and
+// stores the result in the value pointed to by result.
+func DecodeWith[S any](reader io.Reader, format Format, result *S, opts ...func(*codec.DecodeOption)) error {
+	switch format {
+	case JSON:
+		return json.DecodeWith(reader, result, opts...)
+	case YAML:
+		return yaml.DecodeWith(reader, result, opts...)
+	case TOML:
+		return toml.DecodeWith(reader, result, opts...)
+	}
+	return FormatError{format: string(format)}
+}
+
+// Encode encodes data to the writer with the codec of the format.
+func Encode[S any](writer io.Writer, format Format, data S) error {
+	switch format {
+	case JSON:
+		return json.Encode(writer, data, func(opt *json.EncodeOption) {
+			opt.IndentValue = "  "
+		})
+	case YAML:
+		return yaml.Encode(writer, data)
+	case TOML:
+		return toml.Encode(writer, data)
+	}
+	return FormatError{format: string(format)}
+}
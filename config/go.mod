@@ -0,0 +1,37 @@
+module github.com/shangkuei/gap/config
+
+go 1.22
+
+require (
+	github.com/creasty/defaults v1.7.0
+	github.com/goccy/go-yaml v1.11.3
+	github.com/mitchellh/mapstructure v1.5.0
+	github.com/pelletier/go-toml/v2 v2.2.2
+	github.com/shangkuei/gap/codec v0.0.1
+	github.com/shangkuei/gap/json v0.0.1
+	github.com/shangkuei/gap/testhelper v0.0.1
+	github.com/shangkuei/gap/toml v0.0.1
+	github.com/shangkuei/gap/yaml v0.0.1
+	github.com/spf13/afero v1.11.0
+)
+
+require (
+	github.com/fatih/color v1.17.0 // indirect
+	github.com/gabriel-vasile/mimetype v1.4.4 // indirect
+	github.com/go-playground/validator/v10 v10.20.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	golang.org/x/crypto v0.23.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	golang.org/x/xerrors v0.0.0-20231012003039-104605ab7028 // indirect
+)
+
+replace (
+	github.com/shangkuei/gap/codec => ../codec
+	github.com/shangkuei/gap/json => ../json
+	github.com/shangkuei/gap/testhelper => ../testhelper
+	github.com/shangkuei/gap/toml => ../toml
+	github.com/shangkuei/gap/yaml => ../yaml
+)
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shangkuei/gap/codec"
+)
+
+// mediaTypes maps the media types of the Content-Type header to the formats.
+var mediaTypes = map[string]Format{
+	"application/json":   JSON,
+	"text/json":          JSON,
+	"application/yaml":   YAML,
+	"application/x-yaml": YAML,
+	"text/yaml":          YAML,
+	"text/x-yaml":        YAML,
+	"application/toml":   TOML,
+	"text/toml":          TOML,
+	"text/x-toml":        TOML,
+}
+
+// RemoteError holds an error related to fetching a remote document.
+type RemoteError struct {
+	url string
+	err error
+}
+
+// Error returns the error in string format.
+func (e RemoteError) Error() string {
+	return fmt.Sprintf("remote(%s)::%s", e.url, e.err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e RemoteError) Unwrap() error {
+	return e.err
+}
+
+// RemoteOption is a type for functional options for the NewRemote function.
+type RemoteOption struct {
+	// Client sends the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+	// Header is added to the requests, e.g. for authorization.
+	Header http.Header
+	// Format is the format of the document if the Content-Type is not of a known format. It
+	// defaults to the format of the extension of the URL path.
+	Format Format
+}
+
+// Remote is a source of a document served over HTTP. It keeps the last fetched copy, served as
+// long as it is fresh by the Cache-Control header, then revalidated with its ETag. If a fetch
+// fails, the last copy is served instead with a warning logged by slog.
+type Remote struct {
+	url string
+	opt RemoteOption
+	now func() time.Time
+
+	mu   sync.Mutex
+	last *remoteCopy
+}
+
+type remoteCopy struct {
+	data    []byte
+	format  Format
+	etag    string
+	expires time.Time
+}
+
+// NewRemote creates a Remote fetching the URL.
+func NewRemote(url string, opts ...func(*RemoteOption)) *Remote {
+	var opt RemoteOption
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	if opt.Client == nil {
+		opt.Client = http.DefaultClient
+	}
+	return &Remote{url: url, opt: opt, now: time.Now}
+}
+
+// Fetch returns the remote document.
+func (r *Remote) Fetch(ctx context.Context) (Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last, err := r.fetch(ctx)
+	if err != nil {
+		if r.last == nil {
+			return Document{}, RemoteError{url: r.url, err: err}
+		}
+		slog.Warn("remote config unavailable, using the cached copy", "url", r.url, "error", err)
+		last = r.last
+	}
+	return Document{Reader: bytes.NewReader(last.data), Format: last.format}, nil
+}
+
+// fetch returns the cached copy while it is fresh, and requests the document otherwise.
+func (r *Remote) fetch(ctx context.Context) (*remoteCopy, error) {
+	now := r.now()
+	if r.last != nil && now.Before(r.last.expires) {
+		return r.last, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.opt.Header {
+		request.Header[key] = values
+	}
+	if r.last != nil && r.last.etag != "" {
+		request.Header.Set("If-None-Match", r.last.etag)
+	}
+	response, err := r.opt.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	maxAge, store := cacheControl(response.Header.Get("Cache-Control"))
+	switch {
+	case response.StatusCode == http.StatusNotModified && r.last != nil:
+		r.last.expires = now.Add(maxAge)
+		return r.last, nil
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	format, err := r.format(response.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	last := &remoteCopy{
+		data:    data,
+		format:  format,
+		etag:    response.Header.Get("ETag"),
+		expires: now.Add(maxAge),
+	}
+	if store {
+		r.last = last
+	} else {
+		r.last = nil
+	}
+	return last, nil
+}
+
+// format returns the format of the document served with the Content-Type.
+func (r *Remote) format(contentType string) (Format, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if format, ok := mediaTypes[mediaType]; ok {
+			return format, nil
+		}
+		if strings.HasSuffix(mediaType, "+json") {
+			return JSON, nil
+		}
+		if strings.HasSuffix(mediaType, "+yaml") {
+			return YAML, nil
+		}
+	}
+	if r.opt.Format != "" {
+		return r.opt.Format, nil
+	}
+	if u, err := url.Parse(r.url); err == nil {
+		if format, err := FormatFromPath(path.Base(u.Path)); err == nil {
+			return format, nil
+		}
+	}
+	return "", FormatError{format: contentType}
+}
+
+// cacheControl returns how long a response stays fresh and whether it may be stored, from its
+// Cache-Control header.
+func cacheControl(header string) (time.Duration, bool) {
+	var maxAge time.Duration
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return 0, false
+		case "no-cache":
+			return 0, true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return maxAge, true
+}
+
+// Poll fetches the document at every interval, and calls onChange with the first document and
+// then each time its content changes. It blocks until the context is done.
+func (r *Remote) Poll(ctx context.Context, interval time.Duration, onChange func(Document)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		if document, err := r.Fetch(ctx); err != nil {
+			slog.Warn("remote config unavailable", "url", r.url, "error", err)
+		} else if data, _ := io.ReadAll(document.Reader); last == nil || !bytes.Equal(data, last) {
+			last = data
+			onChange(Document{Reader: bytes.NewReader(data), Format: document.Format})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DecodeRemote fetches the remote document and decodes it with the codec of its format into the
+// value pointed to by result. The URL is the Source of the document in warnings.
+func DecodeRemote[S any](ctx context.Context, remote *Remote, result *S, opts ...func(*codec.DecodeOption)) error {
+	document, err := remote.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	opts = append([]func(*codec.DecodeOption){codec.WithSource(remote.url)}, opts...)
+	return DecodeWith(document.Reader, document.Format, result, opts...)
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type remoteConfig struct {
+	Level string `mapstructure:"level"`
+}
+
+// remoteServer serves a document and records the requests.
+type remoteServer struct {
+	mu           sync.Mutex
+	body         string
+	contentType  string
+	etag         string
+	cacheControl string
+	fail         bool
+	requests     []string
+}
+
+func (s *remoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Header.Get("If-None-Match"))
+	if s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if s.cacheControl != "" {
+		w.Header().Set("Cache-Control", s.cacheControl)
+	}
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", s.contentType)
+	_, _ = w.Write([]byte(s.body))
+}
+
+func (s *remoteServer) set(fn func(s *remoteServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+func (s *remoteServer) takeRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func TestRemoteFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		opt         RemoteOption
+		body        string
+		want        remoteConfig
+		wantErr     error
+	}{
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"level":"debug"}`,
+			want:        remoteConfig{Level: "debug"},
+		},
+		{
+			name:        "yaml",
+			contentType: "application/x-yaml",
+			body:        "level: debug\n",
+			want:        remoteConfig{Level: "debug"},
+		},
+		{
+			name:        "toml",
+			contentType: "application/toml",
+			body:        "level = 'debug'\n",
+			want:        remoteConfig{Level: "debug"},
+		},
+		{
+			name:        "structured syntax suffix",
+			contentType: "application/vnd.app.config+json",
+			body:        `{"level":"debug"}`,
+			want:        remoteConfig{Level: "debug"},
+		},
+		{
+			name:        "format option",
+			contentType: "text/plain",
+			opt:         RemoteOption{Format: TOML},
+			body:        "level = 'debug'\n",
+			want:        remoteConfig{Level: "debug"},
+		},
+		{
+			name:        "path extension",
+			path:        "/config.yaml",
+			contentType: "text/plain",
+			body:        "level: debug\n",
+			want:        remoteConfig{Level: "debug"},
+		},
+		{
+			name:        "unknown format",
+			contentType: "text/plain",
+			body:        "level: debug\n",
+			wantErr:     FormatError{format: "text/plain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(&remoteServer{body: tt.body, contentType: tt.contentType})
+			defer server.Close()
+
+			remote := NewRemote(server.URL+tt.path, func(opt *RemoteOption) {
+				*opt = tt.opt
+			})
+			var got remoteConfig
+			err := DecodeRemote(context.Background(), remote, &got)
+			if tt.wantErr != nil {
+				if diff, ok := helper.Equal(errors.Is(err, tt.wantErr), true); !ok {
+					t.Fatal(helper.Message(t, "unexpected error", diff, fmt.Sprint(err)))
+				}
+				return
+			}
+			if diff, ok := helper.Equal(err, error(nil)); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+		})
+	}
+}
+
+func TestRemoteCache(t *testing.T) {
+	handler := &remoteServer{body: `{"level":"debug"}`, contentType: "application/json", etag: `"v1"`}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	remote := NewRemote(server.URL)
+	remote.now = func() time.Time { return now }
+
+	steps := []struct {
+		name         string
+		update       func(s *remoteServer)
+		elapsed      time.Duration
+		want         remoteConfig
+		wantRequests []string
+		wantErr      bool
+	}{
+		{
+			name:         "first fetch",
+			want:         remoteConfig{Level: "debug"},
+			wantRequests: []string{""},
+		},
+		{
+			name:         "revalidate without max-age",
+			want:         remoteConfig{Level: "debug"},
+			wantRequests: []string{`"v1"`},
+		},
+		{
+			name: "changed",
+			update: func(s *remoteServer) {
+				s.body, s.etag, s.cacheControl = `{"level":"info"}`, `"v2"`, "public, max-age=60"
+			},
+			want:         remoteConfig{Level: "info"},
+			wantRequests: []string{`"v1"`},
+		},
+		{
+			name:    "fresh",
+			update:  func(s *remoteServer) { s.body, s.etag = `{"level":"warn"}`, `"v3"` },
+			elapsed: 30 * time.Second,
+			want:    remoteConfig{Level: "info"},
+		},
+		{
+			name:         "stale",
+			elapsed:      time.Minute,
+			want:         remoteConfig{Level: "warn"},
+			wantRequests: []string{`"v2"`},
+		},
+		{
+			name:         "fall back to the cached copy",
+			update:       func(s *remoteServer) { s.fail = true },
+			elapsed:      time.Minute,
+			want:         remoteConfig{Level: "warn"},
+			wantRequests: []string{`"v3"`},
+		},
+		{
+			name: "no-store",
+			update: func(s *remoteServer) {
+				s.fail, s.body, s.etag, s.cacheControl = false, `{"level":"error"}`, "", "no-store"
+			},
+			elapsed:      time.Minute,
+			want:         remoteConfig{Level: "error"},
+			wantRequests: []string{`"v3"`},
+		},
+		{
+			name:         "failure without cached copy",
+			update:       func(s *remoteServer) { s.fail = true },
+			wantRequests: []string{""},
+			wantErr:      true,
+		},
+	}
+	for _, step := range steps {
+		if step.update != nil {
+			handler.set(step.update)
+		}
+		now = now.Add(step.elapsed)
+
+		var got remoteConfig
+		err := DecodeRemote(context.Background(), remote, &got)
+		if diff, ok := helper.Equal(err != nil, step.wantErr); !ok {
+			t.Fatal(helper.Message(t, "unexpected error", diff, step.name, fmt.Sprint(err)))
+		}
+		if diff, ok := helper.Equal(got, step.want); !ok {
+			t.Error(helper.Message(t, "unexpected result", diff, step.name))
+		}
+		if diff, ok := helper.Equal(handler.takeRequests(), step.wantRequests); !ok {
+			t.Error(helper.Message(t, "unexpected requests", diff, step.name))
+		}
+	}
+}
+
+func TestRemotePoll(t *testing.T) {
+	handler := &remoteServer{body: `{"level":"debug"}`, contentType: "application/json"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan remoteConfig)
+	done := make(chan error)
+	go func() {
+		done <- NewRemote(server.URL).Poll(ctx, time.Millisecond, func(document Document) {
+			var config remoteConfig
+			if err := Decode(document.Reader, document.Format, &config); err != nil {
+				t.Error(err)
+			}
+			changes <- config
+		})
+	}()
+
+	if diff, ok := helper.Equal(<-changes, remoteConfig{Level: "debug"}); !ok {
+		t.Error(helper.Message(t, "unexpected first document", diff))
+	}
+	handler.set(func(s *remoteServer) { s.body = `{"level":"info"}` })
+	if diff, ok := helper.Equal(<-changes, remoteConfig{Level: "info"}); !ok {
+		t.Error(helper.Message(t, "unexpected changed document", diff))
+	}
+	if len(handler.takeRequests()) < 2 {
+		t.Error(helper.Message(t, "unexpected requests"))
+	}
+
+	cancel()
+	if diff, ok := helper.Equal(errors.Is(<-done, context.Canceled), true); !ok {
+		t.Error(helper.Message(t, "unexpected error", diff))
+	}
+}
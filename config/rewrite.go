@@ -0,0 +1,249 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// rewrite replaces the bytes of a value in a document.
+type rewrite struct {
+	start, end int
+	value      string
+}
+
+// rewriteStrings returns the encoded document with its string values replaced by transform,
+// given the keys of the maps holding them. Only the replaced values are rewritten, so that the
+// rest of the document, e.g. its comments and the order of its keys, is kept as it is.
+func rewriteStrings(raw []byte, format Format, transform func(keys []string, value string) (string, error)) ([]byte, error) {
+	switch format {
+	case JSON:
+		return rewriteJSON(raw, transform)
+	case YAML:
+		return rewriteYAML(raw, transform)
+	case TOML:
+		return rewriteTOML(raw, transform)
+	}
+	return nil, FormatError{format: string(format)}
+}
+
+// applyRewrites returns the document with the rewrites, which are in order, applied.
+func applyRewrites(raw []byte, rewrites []rewrite) []byte {
+	var buf bytes.Buffer
+	last := 0
+	for _, r := range rewrites {
+		buf.Write(raw[last:r.start])
+		buf.WriteString(r.value)
+		last = r.end
+	}
+	buf.Write(raw[last:])
+	return buf.Bytes()
+}
+
+// quoteString quotes the value as a json string, which is a valid yaml double-quoted string and
+// toml basic string too.
+func quoteString(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func rewriteJSON(raw []byte, transform func(keys []string, value string) (string, error)) ([]byte, error) {
+	var rewrites []rewrite
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if err := rewriteJSONValue(decoder, raw, nil, transform, &rewrites); err != nil {
+		return nil, err
+	}
+	return applyRewrites(raw, rewrites), nil
+}
+
+func rewriteJSONValue(decoder *json.Decoder, raw []byte, keys []string, transform func(keys []string, value string) (string, error), rewrites *[]rewrite) error {
+	offset := decoder.InputOffset()
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case string:
+		value, err := transform(keys, tok)
+		if err != nil || value == tok {
+			return err
+		}
+		end := int(decoder.InputOffset())
+		start := int(offset) + bytes.IndexByte(raw[offset:end], '"')
+		*rewrites = append(*rewrites, rewrite{start: start, end: end, value: quoteString(value)})
+	case json.Delim:
+		for decoder.More() {
+			path := keys
+			if tok == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				path = append(keys[:len(keys):len(keys)], key.(string))
+			}
+			if err := rewriteJSONValue(decoder, raw, path, transform, rewrites); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	}
+	return err
+}
+
+func rewriteYAML(raw []byte, transform func(keys []string, value string) (string, error)) ([]byte, error) {
+	file, err := parser.ParseBytes(raw, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var changed bool
+	for _, doc := range file.Docs {
+		body, err := rewriteYAMLNode(doc.Body, nil, transform, &changed)
+		if err != nil {
+			return nil, err
+		}
+		doc.Body = body
+	}
+	if !changed {
+		return raw, nil
+	}
+	return []byte(file.String()), nil
+}
+
+// rewriteYAMLNode rewrites the string values in the node, and returns the node to replace it
+// with, e.g. a double-quoted string replacing a block scalar.
+func rewriteYAMLNode(node ast.Node, keys []string, transform func(keys []string, value string) (string, error), changed *bool) (ast.Node, error) {
+	switch node := node.(type) {
+	case *ast.StringNode, *ast.LiteralNode:
+		var original string
+		if literal, ok := node.(*ast.LiteralNode); ok {
+			original = literal.Value.Value
+		} else {
+			original = node.(*ast.StringNode).Value
+		}
+		value, err := transform(keys, original)
+		if err != nil || value == original {
+			return node, err
+		}
+		*changed = true
+		position := *node.GetToken().Position
+		rewritten := ast.String(&token.Token{
+			Type:          token.DoubleQuoteType,
+			CharacterType: token.CharacterTypeIndicator,
+			Indicator:     token.QuotedScalarIndicator,
+			Value:         value,
+			Origin:        quoteString(value),
+			Position:      &position,
+		})
+		// The comment following the value is kept.
+		return rewritten, rewritten.SetComment(node.GetComment())
+	case *ast.AnchorNode:
+		value, err := rewriteYAMLNode(node.Value, keys, transform, changed)
+		node.Value = value
+		return node, err
+	case *ast.TagNode:
+		value, err := rewriteYAMLNode(node.Value, keys, transform, changed)
+		node.Value = value
+		return node, err
+	case *ast.MappingNode:
+		for _, value := range node.Values {
+			if _, err := rewriteYAMLNode(value, keys, transform, changed); err != nil {
+				return node, err
+			}
+		}
+	case *ast.MappingValueNode:
+		key := node.Key.GetToken()
+		if key == nil {
+			return node, nil
+		}
+		value, err := rewriteYAMLNode(node.Value, append(keys[:len(keys):len(keys)], key.Value), transform, changed)
+		if err != nil {
+			return node, err
+		}
+		if value != node.Value {
+			return node, node.Replace(value)
+		}
+	case *ast.SequenceNode:
+		for i, value := range node.Values {
+			rewritten, err := rewriteYAMLNode(value, keys, transform, changed)
+			if err != nil {
+				return node, err
+			}
+			if rewritten != value {
+				if err := node.Replace(i, rewritten); err != nil {
+					return node, err
+				}
+			}
+		}
+	}
+	return node, nil
+}
+
+func rewriteTOML(raw []byte, transform func(keys []string, value string) (string, error)) ([]byte, error) {
+	var rewrites []rewrite
+	parser := unstable.Parser{}
+	parser.Reset(raw)
+	var table []string
+	for parser.NextExpression() {
+		expression := parser.Expression()
+		switch expression.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = appendTOMLKey(nil, expression.Key())
+		case unstable.KeyValue:
+			if err := rewriteTOMLKeyValue(expression, table, transform, &rewrites); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := parser.Error(); err != nil {
+		return nil, err
+	}
+	return applyRewrites(raw, rewrites), nil
+}
+
+// appendTOMLKey appends the parts of a dotted key to the keys.
+func appendTOMLKey(keys []string, key unstable.Iterator) []string {
+	keys = keys[:len(keys):len(keys)]
+	for key.Next() {
+		keys = append(keys, string(key.Node().Data))
+	}
+	return keys
+}
+
+func rewriteTOMLKeyValue(node *unstable.Node, keys []string, transform func(keys []string, value string) (string, error), rewrites *[]rewrite) error {
+	return rewriteTOMLValue(node.Value(), appendTOMLKey(keys, node.Key()), transform, rewrites)
+}
+
+func rewriteTOMLValue(node *unstable.Node, keys []string, transform func(keys []string, value string) (string, error), rewrites *[]rewrite) error {
+	switch node.Kind {
+	case unstable.String:
+		value, err := transform(keys, string(node.Data))
+		if err != nil || value == string(node.Data) {
+			return err
+		}
+		start := int(node.Raw.Offset)
+		*rewrites = append(*rewrites, rewrite{start: start, end: start + int(node.Raw.Length), value: quoteString(value)})
+	case unstable.InlineTable:
+		children := node.Children()
+		for children.Next() {
+			if err := rewriteTOMLKeyValue(children.Node(), keys, transform, rewrites); err != nil {
+				return err
+			}
+		}
+	case unstable.Array:
+		children := node.Children()
+		for children.Next() {
+			if err := rewriteTOMLValue(children.Node(), keys, transform, rewrites); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/shangkuei/gap/codec"
+)
+
+// KeyEnv is the environment variable holding the key of the secrets if no key file is given.
+const KeyEnv = "GAP_CONFIG_KEY"
+
+// encryptedValue matches a value encrypted by Secrets, e.g. ENC[AES256_GCM,data:...,iv:...,tag:...,type:str].
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:([A-Za-z0-9+/=]*),iv:([A-Za-z0-9+/=]+),tag:([A-Za-z0-9+/=]+),type:str\]$`)
+
+// SecretError holds an error related to an encrypted value.
+type SecretError struct {
+	err error
+}
+
+// Error returns the error in string format.
+func (e SecretError) Error() string {
+	return fmt.Sprintf("secret::%s", e.err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e SecretError) Unwrap() error {
+	return e.err
+}
+
+// Secrets encrypts and decrypts the string values of documents in place with AES-256-GCM, so
+// that secrets are committed along with the other values, which stay readable. An encrypted value
+// looks like ENC[AES256_GCM,data:...,iv:...,tag:...,type:str], and is bound to the path of its
+// key, see Encrypt.
+type Secrets struct {
+	aead cipher.AEAD
+}
+
+// NewSecrets creates Secrets with a key of 32 bytes.
+func NewSecrets(key []byte) (*Secrets, error) {
+	if len(key) != 32 {
+		return nil, SecretError{err: fmt.Errorf("key of %d bytes, expected 32", len(key))}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, SecretError{err: err}
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, SecretError{err: err}
+	}
+	return &Secrets{aead: aead}, nil
+}
+
+// LoadSecrets creates Secrets with the base64 encoded key read from the key file, or from the
+// KeyEnv environment variable if the path is empty.
+func LoadSecrets(keyFile string) (*Secrets, error) {
+	encoded := os.Getenv(KeyEnv)
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, SecretError{err: err}
+		}
+		encoded = string(data)
+	}
+	if strings.TrimSpace(encoded) == "" {
+		return nil, SecretError{err: fmt.Errorf("no key file and %s is empty", KeyEnv)}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, SecretError{err: fmt.Errorf("invalid key: %w", err)}
+	}
+	return NewSecrets(key)
+}
+
+// GenerateKey returns a new random key, base64 encoded as LoadSecrets reads it.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", SecretError{err: err}
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted reports whether the value is encrypted.
+func IsEncrypted(value string) bool {
+	return encryptedValue.MatchString(value)
+}
+
+// Encrypt encrypts the value of the key at the path, which is authenticated along with it so
+// that the encrypted value cannot be moved to another key. An encrypted value is returned as it
+// is.
+func (s *Secrets) Encrypt(path, value string) (string, error) {
+	if IsEncrypted(value) {
+		return value, nil
+	}
+
+	iv := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", SecretError{err: err}
+	}
+	sealed := s.aead.Seal(nil, iv, []byte(value), []byte(path))
+	data, tag := sealed[:len(sealed)-s.aead.Overhead()], sealed[len(sealed)-s.aead.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag)), nil
+}
+
+// Decrypt decrypts the value of the key at the path it was encrypted for. A value which is not
+// encrypted is returned as it is.
+func (s *Secrets) Decrypt(path, value string) (string, error) {
+	match := encryptedValue.FindStringSubmatch(value)
+	if match == nil {
+		return value, nil
+	}
+
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		if parts[i], err = base64.StdEncoding.DecodeString(match[i+1]); err != nil {
+			return "", SecretError{err: err}
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	if len(iv) != s.aead.NonceSize() {
+		return "", SecretError{err: fmt.Errorf("iv of %d bytes, expected %d", len(iv), s.aead.NonceSize())}
+	}
+	plaintext, err := s.aead.Open(nil, iv, append(data, tag...), []byte(path))
+	if err != nil {
+		return "", SecretError{err: fmt.Errorf("%s: %w", path, err)}
+	}
+	return string(plaintext), nil
+}
+
+// DecodeHook returns a DecodeHookFunc decrypting the encrypted string values. The first map or
+// slice the hook is called with, i.e. the decoded document, is decrypted as a whole since the
+// hook is not given the paths of the values.
+func (s *Secrets) DecodeHook() mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data any) (any, error) {
+		switch data.(type) {
+		case map[string]any, []any:
+			return s.DecryptTree(data)
+		}
+		return data, nil
+	}
+}
+
+// EncryptTree returns the document tree with its string values encrypted if the key of one of
+// their parents matches the pattern, leaving data untouched.
+func (s *Secrets) EncryptTree(data any, pattern *regexp.Regexp) (any, error) {
+	return transformTree(data, nil, func(keys []string, value string) (string, error) {
+		if !matchKeys(keys, pattern) {
+			return value, nil
+		}
+		return s.Encrypt(secretPath(keys), value)
+	})
+}
+
+// DecryptTree returns the document tree with its encrypted string values decrypted, leaving data
+// untouched.
+func (s *Secrets) DecryptTree(data any) (any, error) {
+	return transformTree(data, nil, func(keys []string, value string) (string, error) {
+		return s.Decrypt(secretPath(keys), value)
+	})
+}
+
+// EncryptDocument encrypts the string values of the encoded document like EncryptTree, rewriting
+// them in place so that the rest of the document, e.g. its comments, is kept as it is.
+func (s *Secrets) EncryptDocument(raw []byte, format Format, pattern *regexp.Regexp) ([]byte, error) {
+	return rewriteStrings(raw, format, func(keys []string, value string) (string, error) {
+		if !matchKeys(keys, pattern) {
+			return value, nil
+		}
+		return s.Encrypt(secretPath(keys), value)
+	})
+}
+
+// DecryptDocument decrypts the encrypted string values of the encoded document, rewriting them in
+// place so that the rest of the document, e.g. its comments, is kept as it is.
+func (s *Secrets) DecryptDocument(raw []byte, format Format) ([]byte, error) {
+	return rewriteStrings(raw, format, func(keys []string, value string) (string, error) {
+		return s.Decrypt(secretPath(keys), value)
+	})
+}
+
+// secretPath returns the path of a value authenticated with it, made of the keys of the maps
+// holding it joined with dots. The indices of the slices are left out, so that the values of a
+// slice can be reordered, and so is the profile of a value in the profiles block, e.g.
+// profiles.prod.db.password is db.password, so that it is decrypted once merged by the profile.
+func secretPath(keys []string) string {
+	if len(keys) > 2 && keys[0] == codec.ProfilesKey {
+		keys = keys[2:]
+	}
+	return strings.Join(keys, ".")
+}
+
+// matchKeys reports whether one of the keys matches the pattern.
+func matchKeys(keys []string, pattern *regexp.Regexp) bool {
+	for _, key := range keys {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// transformTree returns the document tree with its string values replaced by transform, given
+// the keys of the maps holding them, leaving data untouched.
+func transformTree(data any, keys []string, transform func(keys []string, value string) (string, error)) (any, error) {
+	transformed, _, err := transformValue(data, keys, transform)
+	return transformed, err
+}
+
+// transformValue is transformTree, which copies the maps and slices only if their values change,
+// and reports whether they did.
+func transformValue(data any, keys []string, transform func(keys []string, value string) (string, error)) (any, bool, error) {
+	switch data := data.(type) {
+	case string:
+		transformed, err := transform(keys, data)
+		return transformed, err == nil && transformed != data, err
+	case map[string]any:
+		var copied map[string]any
+		for key, value := range data {
+			transformed, changed, err := transformValue(value, append(keys[:len(keys):len(keys)], key), transform)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if copied == nil {
+					copied = maps.Clone(data)
+				}
+				copied[key] = transformed
+			}
+		}
+		if copied != nil {
+			return copied, true, nil
+		}
+	case []any:
+		var copied []any
+		for i, value := range data {
+			transformed, changed, err := transformValue(value, keys, transform)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if copied == nil {
+					copied = slices.Clone(data)
+				}
+				copied[i] = transformed
+			}
+		}
+		if copied != nil {
+			return copied, true, nil
+		}
+	}
+	return data, false, nil
+}
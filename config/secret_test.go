@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/shangkuei/gap/codec"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type secretDatabase struct {
+	Host     string `mapstructure:"host"`
+	Password string `mapstructure:"password"`
+}
+
+type secretConfig struct {
+	Level    string         `mapstructure:"level"`
+	Database secretDatabase `mapstructure:"database"`
+	Tokens   []string       `mapstructure:"tokens"`
+}
+
+func newTestSecrets(t *testing.T) *Secrets {
+	t.Helper()
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(KeyEnv, key)
+	secrets, err := LoadSecrets("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secrets
+}
+
+func TestSecrets(t *testing.T) {
+	secrets := newTestSecrets(t)
+
+	for _, value := range []string{"", "hunter2", "ünïcode ✓"} {
+		encrypted, err := secrets.Encrypt("database.password", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(IsEncrypted(encrypted), true); !ok {
+			t.Error(helper.Message(t, "unexpected encrypted value", diff, encrypted))
+		}
+		again, err := secrets.Encrypt("database.password", encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(again, encrypted); !ok {
+			t.Error(helper.Message(t, "encrypted value encrypted again", diff))
+		}
+		decrypted, err := secrets.Decrypt("database.password", encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(decrypted, value); !ok {
+			t.Error(helper.Message(t, "unexpected decrypted value", diff))
+		}
+	}
+
+	plain, err := secrets.Decrypt("database.password", "ENC[not encrypted]")
+	if diff, ok := helper.Equal(plain, "ENC[not encrypted]"); !ok || err != nil {
+		t.Error(helper.Message(t, "unexpected plain value", diff))
+	}
+
+	encrypted, err := secrets.Encrypt("database.password", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := regexp.MustCompile(`iv:[^,]+`).ReplaceAllString(encrypted, "iv:AAAAAAAAAAAAAAAA")
+	if _, err := secrets.Decrypt("database.password", tampered); !errors.As(err, &SecretError{}) {
+		t.Error(helper.Message(t, "tampered value decrypted", tampered))
+	}
+	if _, err := secrets.Decrypt("database.token", encrypted); !errors.As(err, &SecretError{}) {
+		t.Error(helper.Message(t, "value decrypted for another key"))
+	}
+	other := newTestSecrets(t)
+	if _, err := other.Decrypt("database.password", encrypted); !errors.As(err, &SecretError{}) {
+		t.Error(helper.Message(t, "value decrypted with another key"))
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	shortFile := filepath.Join(dir, "short")
+	if err := os.WriteFile(shortFile, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyFile string
+		env     string
+		wantErr bool
+	}{
+		{name: "key file", keyFile: keyFile},
+		{name: "environment variable", env: key},
+		{name: "key file over environment variable", keyFile: keyFile, env: "invalid"},
+		{name: "no key", wantErr: true},
+		{name: "missing key file", keyFile: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "invalid key", env: "not base64", wantErr: true},
+		{name: "short key", keyFile: shortFile, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(KeyEnv, tt.env)
+			_, err := LoadSecrets(tt.keyFile)
+			if diff, ok := helper.Equal(err != nil, tt.wantErr); !ok {
+				t.Error(helper.Message(t, "unexpected error", diff))
+			}
+		})
+	}
+}
+
+func TestSecretsDecodeHook(t *testing.T) {
+	secrets := newTestSecrets(t)
+	want := secretConfig{
+		Level:    "info",
+		Database: secretDatabase{Host: "db", Password: "hunter2"},
+		Tokens:   []string{"a", "b"},
+	}
+
+	for _, format := range []Format{JSON, YAML, TOML} {
+		t.Run(string(format), func(t *testing.T) {
+			var tree any
+			if err := Decode(bytes.NewReader(encodeTestdata(t, format, want)), format, &tree); err != nil {
+				t.Fatal(err)
+			}
+			tree, err := secrets.EncryptTree(tree, regexp.MustCompile("password|tokens"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := Encode(&buf, format, tree); err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range []string{"info", "db"} {
+				if !strings.Contains(buf.String(), value) {
+					t.Error(helper.Message(t, "value not readable", value, buf.String()))
+				}
+			}
+			if strings.Contains(buf.String(), "hunter2") {
+				t.Error(helper.Message(t, "value not encrypted", buf.String()))
+			}
+
+			var got secretConfig
+			if err := Decode(&buf, format, &got, secrets.DecodeHook()); err != nil {
+				t.Fatal(err)
+			}
+			if diff, ok := helper.Equal(got, want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+		})
+	}
+}
+
+func TestSecretsSwappedValues(t *testing.T) {
+	secrets := newTestSecrets(t)
+	tree, err := secrets.EncryptTree(map[string]any{
+		"database": map[string]any{"password": "hunter2", "token": "abc"},
+	}, regexp.MustCompile("password|token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := tree.(map[string]any)["database"].(map[string]any)
+	database["password"], database["token"] = database["token"], database["password"]
+
+	var got map[string]any
+	err = codec.Decode(tree, &got, codec.NewDecodeOption(codec.WithHooks(secrets.DecodeHook())))
+	if !errors.As(err, &SecretError{}) {
+		t.Error(helper.Message(t, "swapped values decrypted", fmt.Sprint(got)))
+	}
+}
+
+func TestSecretsProfile(t *testing.T) {
+	secrets := newTestSecrets(t)
+	raw := `level = 'info'
+
+[database]
+password = "hunter2"
+
+[profiles.prod.database]
+password = "correct horse"
+`
+	encrypted, err := secrets.EncryptDocument([]byte(raw), TOML, regexp.MustCompile("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), "hunter2") || strings.Contains(string(encrypted), "correct horse") {
+		t.Fatal(helper.Message(t, "secrets not encrypted", string(encrypted)))
+	}
+
+	for _, tt := range []struct {
+		profile string
+		want    string
+	}{
+		{profile: "", want: "hunter2"},
+		{profile: "prod", want: "correct horse"},
+	} {
+		var got secretConfig
+		err := DecodeWith(bytes.NewReader(encrypted), TOML, &got,
+			codec.WithProfile(tt.profile), codec.WithHooks(secrets.DecodeHook()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(got.Database.Password, tt.want); !ok {
+			t.Error(helper.Message(t, "unexpected password of the profile "+tt.profile, diff))
+		}
+	}
+
+	decrypted, err := secrets.DecryptDocument(encrypted, TOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(string(decrypted), raw); !ok {
+		t.Error(helper.Message(t, "unexpected decrypted document", diff))
+	}
+}
+
+func TestSecretsDocument(t *testing.T) {
+	secrets := newTestSecrets(t)
+	tests := []struct {
+		format Format
+		raw    string
+	}{
+		{
+			format: JSON,
+			raw: `{
+  "level": "info",
+  "database": {"password": "hunter2", "host": "db"},
+  "tokens": ["a", "b"]
+}
+`,
+		},
+		{
+			format: YAML,
+			raw: `# Service configuration.
+level: info # the minimum level
+database:
+  # Keep it secret.
+  password: "hunter2" # not in the logs
+  host: db
+tokens:
+  - "a"
+  - "b"
+`,
+		},
+		{
+			format: TOML,
+			raw: `# Service configuration.
+level = 'info'
+tokens = ["a", "b"]
+
+[database]
+# Keep it secret.
+password = "hunter2"
+host = 'db'
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			encrypted, err := secrets.EncryptDocument([]byte(tt.raw), tt.format, regexp.MustCompile("password|tokens"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines, encryptedLines := strings.Split(tt.raw, "\n"), strings.Split(string(encrypted), "\n")
+			if diff, ok := helper.Equal(len(encryptedLines), len(lines)); !ok {
+				t.Fatal(helper.Message(t, "unexpected lines", diff, string(encrypted)))
+			}
+			for i, line := range lines {
+				secret := strings.Contains(line, "hunter2") || strings.Contains(line, `"a"`) || strings.Contains(line, `"b"`)
+				if diff, ok := helper.Equal(encryptedLines[i] != line, secret); !ok {
+					t.Error(helper.Message(t, "unexpected line", diff, encryptedLines[i]))
+				}
+			}
+
+			var got secretConfig
+			if err := Decode(bytes.NewReader(encrypted), tt.format, &got, secrets.DecodeHook()); err != nil {
+				t.Fatal(err)
+			}
+			want := secretConfig{Level: "info", Database: secretDatabase{Host: "db", Password: "hunter2"}, Tokens: []string{"a", "b"}}
+			if diff, ok := helper.Equal(got, want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+
+			decrypted, err := secrets.DecryptDocument(encrypted, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff, ok := helper.Equal(string(decrypted), tt.raw); !ok {
+				t.Error(helper.Message(t, "unexpected decrypted document", diff))
+			}
+		})
+	}
+}
+
+func encodeTestdata(t *testing.T, format Format, data secretConfig) []byte {
+	t.Helper()
+
+	tree := map[string]any{
+		"level":    data.Level,
+		"database": map[string]any{"host": data.Database.Host, "password": data.Database.Password},
+		"tokens":   []any{data.Tokens[0], data.Tokens[1]},
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, format, tree); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
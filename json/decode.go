@@ -1,22 +1,52 @@
 package json
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/json"
 	"io"
+	"reflect"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/shangkuei/gap/codec"
 )
 
+// DecodeOption is a type for functional options for the DecodeWith function.
+type DecodeOption = codec.DecodeOption
+
+// customTypes are the types which encoding/json decodes in its own way.
+var customTypes = []reflect.Type{
+	reflect.TypeOf((*json.Unmarshaler)(nil)).Elem(),
+	reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem(),
+}
+
 // Decode decodes json encoded data from the reader and stores the result in the value pointed to by result.
 func Decode[S any](reader io.Reader, result *S, hooks ...mapstructure.DecodeHookFunc) error {
-	var data any
-	if err := json.NewDecoder(reader).Decode(&data); err != nil {
+	return DecodeWith(reader, result, codec.WithHooks(hooks...))
+}
+
+// DecodeWith decodes json encoded data from the reader with the options and stores the result in
+// the value pointed to by result. The data is decoded directly into the result if possible, see
+// codec.DecodeOption.Direct.
+func DecodeWith[S any](reader io.Reader, result *S, opts ...func(*DecodeOption)) error {
+	opt := codec.NewDecodeOption(opts...)
+	raw, err := opt.ReadAll(reader)
+	if err != nil {
 		return err
 	}
+	opt.Locate(func(lines codec.KeyLines) { locate(raw, lines) })
+	if opt.Direct(result, []string{"json"}, customTypes...) {
+		// On failure, the document tree is decoded to report all the errors at once.
+		direct := new(S)
+		if err := json.NewDecoder(bytes.NewReader(raw)).Decode(direct); err == nil {
+			*result = *direct
+			return nil
+		}
+	}
 
-	decoder, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		DecodeHook: mapstructure.ComposeDecodeHookFunc(hooks...),
-		Result:     result,
-	})
-	return decoder.Decode(data)
+	var data any
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&data); err != nil {
+		return err
+	}
+	return codec.Decode(data, result, opt)
 }
@@ -0,0 +1,134 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shangkuei/gap/codec"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type benchmarkRecord struct {
+	ID    int               `json:"id" mapstructure:"id"`
+	Name  string            `json:"name" mapstructure:"name"`
+	Score float64           `json:"score" mapstructure:"score"`
+	Tags  []string          `json:"tags" mapstructure:"tags"`
+	Attrs map[string]string `json:"attrs" mapstructure:"attrs"`
+}
+
+type benchmarkDocument struct {
+	Records []benchmarkRecord `json:"records" mapstructure:"records"`
+}
+
+func BenchmarkDecode(b *testing.B) {
+	var document benchmarkDocument
+	for i := 0; i < 100; i++ {
+		document.Records = append(document.Records, benchmarkRecord{
+			ID:    i,
+			Name:  fmt.Sprintf("record-%d", i),
+			Score: float64(i) / 3,
+			Tags:  []string{"a", "b", "c"},
+			Attrs: map[string]string{"key": "value"},
+		})
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, document); err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarks := []struct {
+		name string
+		tree bool
+	}{
+		{name: "tree", tree: true},
+		{name: "direct"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var result benchmarkDocument
+				err := DecodeWith(bytes.NewReader(buf.Bytes()), &result, func(opt *DecodeOption) {
+					opt.Tree = bm.tree
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	raw := `{
+  "level": "info",
+  "servers": [
+    {"host": "localhost", "ports": [80, {"tls": 443}]},
+    {
+      "hostname": "example.com"
+    }
+  ]
+}`
+	lines := make(codec.KeyLines)
+	locate([]byte(raw), lines)
+	want := codec.KeyLines{}
+	want.Set([]string{"level"}, 2)
+	want.Set([]string{"servers"}, 3)
+	want.Set([]string{"servers", "0", "host"}, 4)
+	want.Set([]string{"servers", "0", "ports"}, 4)
+	want.Set([]string{"servers", "0", "ports", "1", "tls"}, 4)
+	want.Set([]string{"servers", "1", "hostname"}, 6)
+	if diff, ok := helper.Equal(lines, want); !ok {
+		t.Error(helper.Message(t, "unexpected lines", diff))
+	}
+}
+
+type directConfig struct {
+	Level string `json:"level" mapstructure:"level"`
+	Port  int    `json:"port" mapstructure:"port"`
+	Data  []byte `json:"data" mapstructure:"data"`
+	Ports [2]int `json:"ports" mapstructure:"ports"`
+}
+
+// TestDecodeDirect checks that decoding directly has the same results as through the document tree.
+func TestDecodeDirect(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		result directConfig
+		want   directConfig
+	}{
+		{name: "valid", data: `{"level": "info", "port": 80}`, want: directConfig{Level: "info", Port: 80}},
+		{name: "key case", data: `{"Level": "info", "PORT": 80}`, want: directConfig{Level: "info", Port: 80}},
+		{name: "invalid", data: `{"port": 80, "level": 123}`, want: directConfig{Port: 80}},
+		{name: "set value", data: `{"port": 80}`, result: directConfig{Level: "info"}, want: directConfig{Level: "info", Port: 80}},
+		{name: "byte slice", data: `{"data": "aGVsbG8="}`},
+		{name: "long array", data: `{"ports": [80, 443, 8080]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]directConfig, 2)
+			errs := make([]string, 2)
+			for i, tree := range []bool{false, true} {
+				results[i] = tt.result
+				err := DecodeWith(strings.NewReader(tt.data), &results[i], func(opt *DecodeOption) {
+					opt.Tree = tree
+				})
+				if err != nil {
+					errs[i] = err.Error()
+				}
+			}
+			if diff, ok := helper.Equal(results[0], tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+			if diff, ok := helper.Equal(results[0], results[1]); !ok {
+				t.Error(helper.Message(t, "direct result differs", diff))
+			}
+			if diff, ok := helper.Equal(errs[0], errs[1]); !ok {
+				t.Error(helper.Message(t, "direct error differs", diff))
+			}
+		})
+	}
+}
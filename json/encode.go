@@ -3,6 +3,9 @@ package json
 import (
 	"encoding/json"
 	"io"
+
+	"github.com/shangkuei/gap/codec"
+	"github.com/spf13/afero"
 )
 
 // EncodeOption is a type for functional options for the Encode function.
@@ -10,6 +13,12 @@ type EncodeOption struct {
 	EscapeHTML   bool
 	IndentPrefix string
 	IndentValue  string
+	// OmitDefaults omits the fields equal to their defaults, see codec.OmitDefaults.
+	OmitDefaults bool
+	// Unions encodes the values of the unions with their discriminators, see codec.EncodeUnions.
+	Unions []codec.Union
+	// File holds the options of EncodeFile.
+	File codec.FileOption
 }
 
 // Encode encodes data to the writer with json.
@@ -19,8 +28,33 @@ func Encode[S any](writer io.Writer, data S, opts ...func(*EncodeOption)) error
 		fn(&opt)
 	}
 
+	value := any(data)
+	if opt.OmitDefaults {
+		var err error
+		if value, err = codec.OmitDefaults(value); err != nil {
+			return err
+		}
+	}
+	value = codec.EncodeUnions(value, opt.Unions...)
+
 	encoder := json.NewEncoder(writer)
 	encoder.SetEscapeHTML(opt.EscapeHTML)
 	encoder.SetIndent(opt.IndentPrefix, opt.IndentValue)
-	return encoder.Encode(data)
+	return encoder.Encode(value)
+}
+
+// EncodeFile atomically writes data encoded with json to the file at path, see codec.WriteFile.
+func EncodeFile[S any](path string, data S, opts ...func(*EncodeOption)) error {
+	return EncodeFileFs(afero.NewOsFs(), path, data, opts...)
+}
+
+// EncodeFileFs is EncodeFile on the file system.
+func EncodeFileFs[S any](fsys afero.Fs, path string, data S, opts ...func(*EncodeOption)) error {
+	var opt EncodeOption
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	return codec.WriteFile(fsys, path, opt.File, func(writer io.Writer) error {
+		return Encode(writer, data, opts...)
+	})
 }
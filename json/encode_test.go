@@ -0,0 +1,110 @@
+package json_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/shangkuei/gap/codec"
+	"github.com/shangkuei/gap/json"
+	"github.com/spf13/afero"
+)
+
+type sampleOutput interface {
+	Describe() string
+}
+
+type sampleFileOutput struct {
+	Path string `json:"path" mapstructure:"path"`
+}
+
+func (o *sampleFileOutput) Describe() string { return "file " + o.Path }
+
+type sampleConsoleOutput struct {
+	Stream string `json:"stream" mapstructure:"stream"`
+}
+
+func (o *sampleConsoleOutput) Describe() string { return "console " + o.Stream }
+
+type sampleOutputs struct {
+	Outputs []sampleOutput `json:"outputs" mapstructure:"outputs"`
+}
+
+func ExampleEncode_unions() {
+	outputs := codec.NewRegistry[sampleOutput]("type").
+		Register("file", &sampleFileOutput{}).
+		Register("console", &sampleConsoleOutput{})
+
+	config := sampleOutputs{Outputs: []sampleOutput{
+		&sampleConsoleOutput{Stream: "stderr"},
+		&sampleFileOutput{Path: "app.log"},
+	}}
+	var buf bytes.Buffer
+	err := json.Encode(&buf, config, func(opt *json.EncodeOption) {
+		opt.Unions = []codec.Union{outputs}
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(buf.String())
+
+	var decoded sampleOutputs
+	if err := json.Decode(&buf, &decoded, outputs.DecodeHook()); err != nil {
+		panic(err)
+	}
+	for _, output := range decoded.Outputs {
+		fmt.Println(output.Describe())
+	}
+
+	// Output:
+	// {"outputs":[{"type":"console","stream":"stderr"},{"type":"file","path":"app.log"}]}
+	// console stderr
+	// file app.log
+}
+
+type sampleServer struct {
+	Host string `json:"host" default:"localhost"`
+	Port int    `json:"port" default:"80"`
+}
+
+func ExampleEncode_omitDefaults() {
+	err := json.Encode(os.Stdout, sampleServer{Host: "localhost", Port: 8080}, func(opt *json.EncodeOption) {
+		opt.OmitDefaults = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// {"port":8080}
+}
+
+func ExampleEncodeFileFs() {
+	fsys := afero.NewMemMapFs()
+	if err := afero.WriteFile(fsys, "server.json", []byte(`{"host":"localhost","port":80}`), 0o640); err != nil {
+		panic(err)
+	}
+
+	err := json.EncodeFileFs(fsys, "server.json", sampleServer{Host: "localhost", Port: 8080}, func(opt *json.EncodeOption) {
+		opt.File.Backup = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, name := range []string{"server.json", "server.json.bak"} {
+		data, err := afero.ReadFile(fsys, name)
+		if err != nil {
+			panic(err)
+		}
+		info, err := fsys.Stat(name)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s %v %s", name, info.Mode(), data)
+	}
+
+	// Output:
+	// server.json -rw-r----- {"host":"localhost","port":8080}
+	// server.json.bak -rw-r----- {"host":"localhost","port":80}
+}
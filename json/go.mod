@@ -4,9 +4,18 @@ go 1.22
 
 require (
 	github.com/mitchellh/mapstructure v1.5.0
+	github.com/shangkuei/gap/codec v0.0.1
 	github.com/shangkuei/gap/testhelper v0.0.1
+	github.com/spf13/afero v1.11.0
 )
 
-require github.com/google/go-cmp v0.6.0 // indirect
+require (
+	github.com/creasty/defaults v1.7.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
 
-replace github.com/shangkuei/gap/testhelper => ../testhelper
+replace (
+	github.com/shangkuei/gap/codec => ../codec
+	github.com/shangkuei/gap/testhelper => ../testhelper
+)
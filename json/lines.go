@@ -0,0 +1,214 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWriterClosed is returned when writing to a closed Writer.
+	ErrWriterClosed = errors.New("lines::writer closed")
+
+	// gzipMagic starts a gzip stream.
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// LinesOption is a type for functional options for the NewWriter function.
+type LinesOption struct {
+	EscapeHTML bool
+	// BufferSize is the size of the buffer of the encoded records. It defaults to 64 KiB.
+	BufferSize int
+	// FlushInterval flushes the buffered records periodically if positive, e.g. for a reader
+	// following the stream.
+	FlushInterval time.Duration
+	// Gzip compresses the stream with gzip.
+	Gzip bool
+}
+
+// Writer encodes a stream of records as JSON Lines, aka NDJSON, i.e. one JSON value per line.
+// It is safe for concurrent use.
+type Writer[S any] struct {
+	mu      sync.Mutex
+	buffer  *bufio.Writer
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+	count   int64
+	err     error
+	closed  bool
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWriter creates a Writer encoding the records to the writer. The Writer must be closed to
+// flush the last records.
+func NewWriter[S any](writer io.Writer, opts ...func(*LinesOption)) *Writer[S] {
+	opt := LinesOption{BufferSize: 64 << 10}
+	for _, fn := range opts {
+		fn(&opt)
+	}
+
+	w := &Writer[S]{}
+	if opt.Gzip {
+		w.gzip = gzip.NewWriter(writer)
+		writer = w.gzip
+	}
+	w.buffer = bufio.NewWriterSize(writer, opt.BufferSize)
+	w.encoder = json.NewEncoder(w.buffer)
+	w.encoder.SetEscapeHTML(opt.EscapeHTML)
+
+	if opt.FlushInterval > 0 {
+		w.stop, w.done = make(chan struct{}), make(chan struct{})
+		go w.flushPeriodically(opt.FlushInterval)
+	}
+	return w
+}
+
+func (w *Writer[S]) flushPeriodically(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			_ = w.Flush()
+		}
+	}
+}
+
+// Write encodes the record as a line.
+func (w *Writer[S]) Write(record S) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if err := w.encoder.Encode(record); err != nil {
+		var unsupported *json.UnsupportedValueError
+		var unsupportedType *json.UnsupportedTypeError
+		var marshaler *json.MarshalerError
+		if !errors.As(err, &unsupported) && !errors.As(err, &unsupportedType) && !errors.As(err, &marshaler) {
+			// The stream is broken by a failed write, unlike a record which can't be encoded.
+			w.err = err
+		}
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Flush writes the buffered records to the underlying writer.
+func (w *Writer[S]) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+func (w *Writer[S]) flush() error {
+	if w.err != nil || w.closed {
+		return w.err
+	}
+	if err := w.buffer.Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	if w.gzip != nil {
+		if err := w.gzip.Flush(); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Count returns the number of records written.
+func (w *Writer[S]) Count() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Close flushes the buffered records and ends the gzip stream if any. It doesn't close the
+// underlying writer.
+func (w *Writer[S]) Close() error {
+	if w.stop != nil {
+		w.stopOnce.Do(func() {
+			close(w.stop)
+			<-w.done
+		})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return w.err
+	}
+	err := w.flush()
+	w.closed = true
+	if w.gzip != nil && err == nil {
+		if err = w.gzip.Close(); err != nil {
+			w.err = err
+		}
+	}
+	return err
+}
+
+// Reader decodes a stream of records encoded as JSON Lines, compressed with gzip or not.
+type Reader[S any] struct {
+	gzip    *gzip.Reader
+	decoder *json.Decoder
+	count   int64
+}
+
+// NewReader creates a Reader decoding the records from the reader. A gzip stream is detected
+// from its header.
+func NewReader[S any](reader io.Reader) (*Reader[S], error) {
+	buffered := bufio.NewReader(reader)
+	r := &Reader[S]{}
+	if magic, err := buffered.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		if r.gzip, err = gzip.NewReader(buffered); err != nil {
+			return nil, err
+		}
+		r.decoder = json.NewDecoder(r.gzip)
+	} else {
+		r.decoder = json.NewDecoder(buffered)
+	}
+	return r, nil
+}
+
+// Read decodes the next record. It returns io.EOF at the end of the stream.
+func (r *Reader[S]) Read() (S, error) {
+	var record S
+	if err := r.decoder.Decode(&record); err != nil {
+		return record, err
+	}
+	r.count++
+	return record, nil
+}
+
+// Count returns the number of records read.
+func (r *Reader[S]) Count() int64 {
+	return r.count
+}
+
+// Close releases the gzip reader if any. It doesn't close the underlying reader.
+func (r *Reader[S]) Close() error {
+	if r.gzip != nil {
+		return r.gzip.Close()
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type linesRecord struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []func(*LinesOption)
+		count int
+	}{
+		{name: "plain", count: 3},
+		{name: "empty"},
+		{name: "gzip", opts: []func(*LinesOption){func(opt *LinesOption) { opt.Gzip = true }}, count: 3},
+		{name: "small buffer", opts: []func(*LinesOption){func(opt *LinesOption) { opt.BufferSize = 16 }}, count: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter[linesRecord](&buf, tt.opts...)
+			var want []linesRecord
+			for i := 0; i < tt.count; i++ {
+				record := linesRecord{ID: i, Name: fmt.Sprintf("record <%d>", i), Score: float64(i) / 2}
+				if err := writer.Write(record); err != nil {
+					t.Fatal(err)
+				}
+				want = append(want, record)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if diff, ok := helper.Equal(writer.Count(), int64(tt.count)); !ok {
+				t.Error(helper.Message(t, "unexpected written count", diff))
+			}
+			if diff, ok := helper.Equal(errors.Is(writer.Write(linesRecord{}), ErrWriterClosed), true); !ok {
+				t.Error(helper.Message(t, "unexpected write after close", diff))
+			}
+
+			reader, err := NewReader[linesRecord](&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			var got []linesRecord
+			for {
+				record, err := reader.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, record)
+			}
+			if diff, ok := helper.Equal(got, want); !ok {
+				t.Error(helper.Message(t, "unexpected records", diff))
+			}
+			if diff, ok := helper.Equal(reader.Count(), int64(tt.count)); !ok {
+				t.Error(helper.Message(t, "unexpected read count", diff))
+			}
+		})
+	}
+}
+
+func TestWriterFlushInterval(t *testing.T) {
+	var buf syncBuffer
+	writer := NewWriter[linesRecord](&buf, func(opt *LinesOption) {
+		opt.FlushInterval = time.Millisecond
+	})
+	defer writer.Close()
+
+	if err := writer.Write(linesRecord{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if diff, ok := helper.Equal(buf.Len() > 0, true); !ok {
+		t.Error(helper.Message(t, "records not flushed", diff))
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter[any](&buf)
+	if err := writer.Write(math.Inf(1)); err == nil {
+		t.Error(helper.Message(t, "unsupported value written"))
+	}
+	if err := writer.Write(1); err != nil {
+		t.Error(helper.Message(t, "unexpected error after an unsupported value", err.Error()))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(buf.String(), "1\n"); !ok {
+		t.Error(helper.Message(t, "unexpected stream", diff))
+	}
+
+	writer = NewWriter[any](failingWriter{}, func(opt *LinesOption) { opt.BufferSize = 16 })
+	if err := writer.Write("a record longer than the buffer"); err == nil {
+		t.Error(helper.Message(t, "failed write succeeded"))
+	}
+	if err := writer.Write(1); err == nil {
+		t.Error(helper.Message(t, "write succeeded after a failed write"))
+	}
+	if err := writer.Close(); err == nil {
+		t.Error(helper.Message(t, "close succeeded after a failed write"))
+	}
+}
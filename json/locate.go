@@ -0,0 +1,46 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/shangkuei/gap/codec"
+)
+
+// locate records the lines of the keys of the json document.
+func locate(raw []byte, lines codec.KeyLines) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	_ = locateValue(decoder, raw, nil, lines)
+}
+
+func locateValue(decoder *json.Decoder, raw []byte, keys []string, lines codec.KeyLines) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			name, _ := key.(string)
+			path := append(keys[:len(keys):len(keys)], name)
+			lines.Set(path, bytes.Count(raw[:decoder.InputOffset()], []byte{'\n'})+1)
+			if err := locateValue(decoder, raw, path, lines); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	case json.Delim('['):
+		for i := 0; decoder.More(); i++ {
+			if err := locateValue(decoder, raw, append(keys[:len(keys):len(keys)], strconv.Itoa(i)), lines); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrHandlerClosed is returned when handling a record with a closed AsyncHandler.
+	ErrHandlerClosed = errors.New("async::handler closed")
+	// ErrCloseTimeout is returned when an AsyncHandler is closed before its queued records are
+	// handled.
+	ErrCloseTimeout = errors.New("async::close timed out")
+)
+
+// Overflow is the policy of an AsyncHandler when its queue is full.
+type Overflow int
+
+const (
+	// OverflowBlock waits for the queue to have room for the record.
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest drops the record.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest record of the queue to make room for the record.
+	OverflowDropOldest
+)
+
+var overflowMapping = map[string]Overflow{
+	"block":      OverflowBlock,
+	"dropnewest": OverflowDropNewest,
+	"dropoldest": OverflowDropOldest,
+}
+
+// AsyncOption is a type for functional options for the NewAsyncHandler function.
+type AsyncOption struct {
+	// QueueSize is the number of records queued before the overflow policy applies. It defaults
+	// to 1024.
+	QueueSize int
+	// Overflow is the policy when the queue is full.
+	Overflow Overflow
+	// CloseTimeout is the maximum duration Close waits for the queued records to be handled, or
+	// forever if it isn't positive. It defaults to 5s.
+	CloseTimeout time.Duration
+}
+
+// AsyncHandler is a slog.Handler passing the records to a handler on its own goroutine, so that
+// logging doesn't wait for the writes. It must be closed to handle the last records.
+type AsyncHandler struct {
+	handler slog.Handler
+	state   *asyncState
+}
+
+type asyncState struct {
+	opt     AsyncOption
+	queue   chan asyncItem
+	done    chan struct{}
+	dropped atomic.Int64
+
+	// closing is closed first by Close, so that the records waiting for room in the queue are
+	// rejected instead of holding up Close.
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+
+	errMu sync.Mutex
+	err   error
+}
+
+type asyncItem struct {
+	ctx     context.Context
+	handler slog.Handler
+	record  slog.Record
+	flushed chan struct{}
+}
+
+// NewAsyncHandler creates an AsyncHandler passing the records to the handler.
+func NewAsyncHandler(handler slog.Handler, opts ...func(*AsyncOption)) *AsyncHandler {
+	opt := AsyncOption{QueueSize: 1024, CloseTimeout: 5 * time.Second}
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 1
+	}
+
+	state := &asyncState{
+		opt:     opt,
+		queue:   make(chan asyncItem, opt.QueueSize),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+	go state.run()
+	return &AsyncHandler{handler: handler, state: state}
+}
+
+func (s *asyncState) run() {
+	defer close(s.done)
+	for item := range s.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		if err := item.handler.Handle(item.ctx, item.record); err != nil {
+			s.errMu.Lock()
+			s.err = errors.Join(s.err, err)
+			s.errMu.Unlock()
+		}
+	}
+}
+
+// Enabled reports whether the handler handles the records at the level.
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle queues the record, or applies the overflow policy if the queue is full. The errors of
+// the handler are returned by Flush and Close.
+func (h *AsyncHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.state.push(asyncItem{ctx: ctx, handler: h.handler, record: record.Clone()})
+}
+
+func (s *asyncState) push(item asyncItem) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrHandlerClosed
+	}
+
+	if item.flushed != nil || s.opt.Overflow == OverflowBlock {
+		return s.wait(item)
+	}
+	// The flush markers are never dropped, so the record is dropped instead once the queue has
+	// been cycled through without finding a record to drop.
+	for markers := 0; ; {
+		select {
+		case s.queue <- item:
+			return nil
+		default:
+		}
+		if s.opt.Overflow == OverflowDropNewest || markers >= cap(s.queue) {
+			s.dropped.Add(1)
+			return nil
+		}
+		select {
+		case oldest := <-s.queue:
+			if oldest.flushed == nil {
+				s.dropped.Add(1)
+				continue
+			}
+			// The marker is queued again behind the records, which it waits for too.
+			markers++
+			if err := s.wait(oldest); err != nil {
+				close(oldest.flushed)
+				return err
+			}
+		default:
+		}
+	}
+}
+
+// wait queues the item, waiting for the queue to have room for it unless the handler is closed.
+func (s *asyncState) wait(item asyncItem) error {
+	select {
+	case s.queue <- item:
+		return nil
+	case <-s.closing:
+		return ErrHandlerClosed
+	}
+}
+
+// Dropped returns the number of records dropped by the overflow policy.
+func (h *AsyncHandler) Dropped() int64 {
+	return h.state.dropped.Load()
+}
+
+// WithAttrs returns an AsyncHandler whose handler has the attributes, which shares the queue.
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{handler: h.handler.WithAttrs(attrs), state: h.state}
+}
+
+// WithGroup returns an AsyncHandler whose handler has the group, which shares the queue.
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &AsyncHandler{handler: h.handler.WithGroup(name), state: h.state}
+}
+
+// Flush waits for the queued records to be handled and flushes the handler. It returns the
+// errors of the handler since the last Flush.
+func (h *AsyncHandler) Flush() error {
+	flushed := make(chan struct{})
+	if err := h.state.push(asyncItem{flushed: flushed}); err != nil {
+		return err
+	}
+	<-flushed
+	return errors.Join(h.state.takeErr(), Flush(h.handler))
+}
+
+// Close handles the queued records, stops the goroutine and flushes the handler. The records
+// waiting for room in the queue and the ones handled afterward are rejected with
+// ErrHandlerClosed. If the queued records aren't handled within CloseTimeout, Close returns
+// ErrCloseTimeout and leaves them to the goroutine.
+func (h *AsyncHandler) Close() error {
+	s := h.state
+	s.closeOnce.Do(func() { close(s.closing) })
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	if s.opt.CloseTimeout > 0 {
+		timer := time.NewTimer(s.opt.CloseTimeout)
+		defer timer.Stop()
+		select {
+		case <-s.done:
+		case <-timer.C:
+			return fmt.Errorf("%w with %d queued records", ErrCloseTimeout, len(s.queue))
+		}
+	}
+	<-s.done
+	return errors.Join(s.takeErr(), Flush(h.handler))
+}
+
+func (s *asyncState) takeErr() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+// gateHandler is a handler recording the messages, which waits for its gate to be opened.
+type gateHandler struct {
+	slog.Handler
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+	err     error
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newGateHandler() *gateHandler {
+	return &gateHandler{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (h *gateHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *gateHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.once.Do(func() { close(h.started) })
+	<-h.gate
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, record.Message)
+	return h.err
+}
+
+func (h *gateHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *gateHandler) Messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.messages
+}
+
+func TestAsyncHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow Overflow
+		want     []string
+		dropped  int64
+	}{
+		{name: "block", overflow: OverflowBlock, want: []string{"0", "1", "2", "3", "4", "5"}},
+		{name: "drop newest", overflow: OverflowDropNewest, want: []string{"0", "1", "2"}, dropped: 3},
+		{name: "drop oldest", overflow: OverflowDropOldest, want: []string{"0", "4", "5"}, dropped: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newGateHandler()
+			handler := NewAsyncHandler(inner, func(opt *AsyncOption) {
+				opt.QueueSize = 2
+				opt.Overflow = tt.overflow
+			})
+			logger := slog.New(handler)
+
+			logger.Info("0")
+			<-inner.started
+			if tt.overflow == OverflowBlock {
+				// The goroutine blocks on the gate, so the queue would block the next records.
+				close(inner.gate)
+			}
+			for i := 1; i < 6; i++ {
+				logger.Info(fmt.Sprint(i))
+			}
+			if tt.overflow != OverflowBlock {
+				close(inner.gate)
+			}
+			if err := handler.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff, ok := helper.Equal(inner.Messages(), tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected messages", diff))
+			}
+			if diff, ok := helper.Equal(handler.Dropped(), tt.dropped); !ok {
+				t.Error(helper.Message(t, "unexpected dropped count", diff))
+			}
+			if err := handler.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAsyncHandlerClose(t *testing.T) {
+	inner := newGateHandler()
+	inner.err = errors.New("write error")
+	close(inner.gate)
+	handler := NewAsyncHandler(inner)
+	logger := slog.New(handler).With("component", "test")
+
+	logger.Info("queued")
+	if err := handler.Close(); err == nil {
+		t.Error(helper.Message(t, "error of the handler not returned"))
+	}
+	if diff, ok := helper.Equal(inner.Messages(), []string{"queued"}); !ok {
+		t.Error(helper.Message(t, "unexpected messages", diff))
+	}
+	if err := logger.Handler().Handle(context.Background(), slog.Record{}); !errors.Is(err, ErrHandlerClosed) {
+		t.Error(helper.Message(t, "record handled after close"))
+	}
+	if err := handler.Close(); err != nil {
+		t.Error(helper.Message(t, "unexpected error closing again", err.Error()))
+	}
+}
+
+func TestAsyncHandlerFlushDropOldest(t *testing.T) {
+	inner := newGateHandler()
+	handler := NewAsyncHandler(inner, func(opt *AsyncOption) {
+		opt.QueueSize = 2
+		opt.Overflow = OverflowDropOldest
+	})
+	logger := slog.New(handler)
+
+	logger.Info("0")
+	<-inner.started
+	flushed := make(chan error)
+	go func() { flushed <- handler.Flush() }()
+	for deadline := time.Now().Add(time.Second); len(handler.state.queue) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal(helper.Message(t, "flush not queued"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i < 4; i++ {
+		logger.Info(fmt.Sprint(i))
+	}
+	select {
+	case <-flushed:
+		t.Fatal(helper.Message(t, "flushed before the queued records were handled"))
+	default:
+	}
+
+	close(inner.gate)
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(inner.Messages(), []string{"0", "3"}); !ok {
+		t.Error(helper.Message(t, "unexpected messages", diff))
+	}
+	if diff, ok := helper.Equal(handler.Dropped(), int64(2)); !ok {
+		t.Error(helper.Message(t, "unexpected dropped count", diff))
+	}
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAsyncHandlerCloseTimeout(t *testing.T) {
+	inner := newGateHandler()
+	defer close(inner.gate)
+	handler := NewAsyncHandler(inner, func(opt *AsyncOption) {
+		opt.QueueSize = 1
+		opt.CloseTimeout = 10 * time.Millisecond
+	})
+	logger := slog.New(handler)
+
+	logger.Info("0")
+	<-inner.started
+	logger.Info("1")
+	blocked := make(chan error)
+	go func() {
+		blocked <- logger.Handler().Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "2", 0))
+	}()
+
+	if err := handler.Close(); !errors.Is(err, ErrCloseTimeout) {
+		t.Error(helper.Message(t, "close not timed out", fmt.Sprint(err)))
+	}
+	if err := <-blocked; !errors.Is(err, ErrHandlerClosed) {
+		t.Error(helper.Message(t, "blocked record not rejected", fmt.Sprint(err)))
+	}
+}
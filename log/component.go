@@ -0,0 +1,108 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// ComponentKey is the key of the attribute naming the component of the logs.
+const ComponentKey = "component"
+
+// Named returns the default logger with the component name, whose level can be set by the levels
+// of the components.
+func Named(name string) *slog.Logger {
+	return slog.Default().With(ComponentKey, name)
+}
+
+// ComponentHandler is a slog.Handler filtering the records by the level of their component, if
+// the Levels override it, or by its own level. The component of a record is the value of its
+// ComponentKey attribute, or else the path of the groups of the handler joined with dots.
+type ComponentHandler struct {
+	handler   slog.Handler
+	level     slog.Leveler
+	levels    *Levels
+	component string
+	groups    []string
+}
+
+// NewComponentHandler creates a ComponentHandler passing the records to the handler.
+func NewComponentHandler(handler slog.Handler, level slog.Leveler, levels *Levels) *ComponentHandler {
+	return &ComponentHandler{handler: handler, level: level, levels: levels}
+}
+
+// Enabled reports whether the handler handles the records at the level. As a record may name its
+// component, it is enabled at the lowest level of the components if the handler has none.
+func (h *ComponentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if name := h.name(); name != "" {
+		return level >= h.threshold(name)
+	}
+	minimum := h.level.Level()
+	if lowest, ok := h.levels.lowestComponent(); ok && lowest < minimum {
+		minimum = lowest
+	}
+	return level >= minimum
+}
+
+// Handle passes the record to the handler if its level is enabled for its component.
+func (h *ComponentHandler) Handle(ctx context.Context, record slog.Record) error {
+	name := h.name()
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == ComponentKey {
+			name = attr.Value.String()
+			return false
+		}
+		return true
+	})
+	if record.Level < h.threshold(name) {
+		return nil
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+// name returns the component of the handler.
+func (h *ComponentHandler) name() string {
+	if h.component != "" {
+		return h.component
+	}
+	return strings.Join(h.groups, ".")
+}
+
+// threshold returns the level of the component, or the level of the handler if not overridden.
+func (h *ComponentHandler) threshold(name string) slog.Level {
+	if level, ok := h.levels.component(name); ok {
+		return level
+	}
+	return h.level.Level()
+}
+
+// WithAttrs returns a ComponentHandler whose handler has the attributes, with the component of
+// the attributes if any.
+func (h *ComponentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *h
+	derived.handler = h.handler.WithAttrs(attrs)
+	if len(h.groups) == 0 {
+		for _, attr := range attrs {
+			if attr.Key == ComponentKey {
+				derived.component = attr.Value.String()
+			}
+		}
+	}
+	return &derived
+}
+
+// WithGroup returns a ComponentHandler whose handler has the group.
+func (h *ComponentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := *h
+	derived.handler = h.handler.WithGroup(name)
+	derived.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &derived
+}
+
+// Flush flushes the handler.
+func (h *ComponentHandler) Flush() error {
+	return Flush(h.handler)
+}
@@ -0,0 +1,157 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+func TestComponentHandler(t *testing.T) {
+	var buf bytes.Buffer
+	var level slog.LevelVar
+	levels := NewLevels()
+	levels.bind(nil, map[string]string{"sqlutil": "debug", "db.*": "trace", "db.pool": "warn"})
+	logger := slog.New(NewComponentHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level:       LevelTrace,
+		ReplaceAttr: replaceLevel(levelNames, false, dropTime),
+	}), &level, levels))
+
+	logger.Debug("hidden")
+	logger.With(ComponentKey, "sqlutil").Debug("named")
+	logger.Debug("attribute", ComponentKey, "sqlutil")
+	logger.WithGroup("db").WithGroup("conn").Log(context.Background(), LevelTrace, "group")
+	logger.WithGroup("db").WithGroup("pool").Info("hidden")
+	logger.WithGroup("db").WithGroup("pool").Warn("exact")
+	logger.With(ComponentKey, "other").Debug("hidden")
+
+	if err := levels.SetComponent("other", slog.LevelDebug); err != nil {
+		t.Fatal(err)
+	}
+	logger.With(ComponentKey, "other").Debug("set")
+	levels.RemoveComponent("other")
+	logger.With(ComponentKey, "other").Debug("hidden")
+	level.Set(slog.LevelError)
+	logger.With(ComponentKey, "sqlutil").Debug("overridden")
+	logger.With(ComponentKey, "other").Warn("hidden")
+	level.Set(slog.LevelInfo)
+	levels.apply(Configuration{SinkConfiguration: SinkConfiguration{Type: "console"}})
+	logger.With(ComponentKey, "sqlutil").Debug("hidden")
+
+	want := []string{
+		"level=DEBUG msg=named component=sqlutil",
+		"level=DEBUG msg=attribute component=sqlutil",
+		"level=TRACE msg=group",
+		"level=WARN msg=exact",
+		"level=DEBUG msg=set component=other",
+		"level=DEBUG msg=overridden component=sqlutil",
+	}
+	if diff, ok := helper.Equal(strings.Split(strings.TrimSpace(buf.String()), "\n"), want); !ok {
+		t.Error(helper.Message(t, "unexpected logs", diff))
+	}
+	if err := levels.SetComponent("[", slog.LevelDebug); err == nil {
+		t.Error(helper.Message(t, "invalid pattern set"))
+	}
+}
+
+func TestComponentHandlerConcurrent(t *testing.T) {
+	var level slog.LevelVar
+	levels := NewLevels()
+	logger := slog.New(NewComponentHandler(slog.NewTextHandler(io.Discard, nil), &level, levels))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := levels.SetComponent(fmt.Sprintf("db.%d", i), slog.LevelDebug); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		logger.Info("query", ComponentKey, fmt.Sprintf("db.%d", i))
+	}
+	<-done
+
+	if diff, ok := helper.Equal(len(levels.Components()), 100); !ok {
+		t.Error(helper.Message(t, "unexpected levels of the components", diff))
+	}
+}
+
+func TestComponentConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	file, output := filepath.Join(dir, "gaplog.toml"), filepath.Join(dir, "app.log")
+	data := "level = \"info\"\ntype = \"file\"\nformat = \"json\"\nfile = " + strconv.Quote(output) +
+		"\n\n[levels]\nsqlutil = \"debug\"\n\"db.*\" = \"trace\"\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ConfigurationFromViper(ViperConfiguration{ConfigFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(config.Components, map[string]string{"sqlutil": "debug", "db.*": "trace"}); !ok {
+		t.Fatal(helper.Message(t, "unexpected levels of the components", diff))
+	}
+
+	levels := NewLevels()
+	config.Levels = levels
+	logger, closer, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]slog.Level{"sqlutil": slog.LevelDebug, "db.*": LevelTrace}
+	if diff, ok := helper.Equal(levels.Components(), want); !ok {
+		t.Error(helper.Message(t, "unexpected levels of the components", diff))
+	}
+
+	logger.With(ComponentKey, "sqlutil").Debug("sqlutil debug")
+	logger.WithGroup("db").WithGroup("conn").Log(context.Background(), LevelTrace, "db trace")
+	logger.With(ComponentKey, "other").Debug("other debug")
+	logger.Debug("debug")
+	logger.With(ComponentKey, "other").Info("other info")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	logs, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(logs)), "\n") {
+		var record struct{ Msg string }
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, record.Msg)
+	}
+	if diff, ok := helper.Equal(messages, []string{"sqlutil debug", "db trace", "other info"}); !ok {
+		t.Error(helper.Message(t, "unexpected logs of the components", diff))
+	}
+
+	config.Components = map[string]string{"sqlutil": "verbose"}
+	if _, _, err := New(config); err == nil {
+		t.Error(helper.Message(t, "invalid level of a component accepted"))
+	}
+	config.Components = map[string]string{"db.[": "debug"}
+	if _, _, err := New(config); err == nil {
+		t.Error(helper.Message(t, "invalid pattern of a component accepted"))
+	}
+
+	data = "level = \"info\"\n\n[levels]\n\"db.[\" = \"debug\"\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConfigurationFromViper(ViperConfiguration{ConfigFile: file}); err == nil {
+		t.Error(helper.Message(t, "invalid pattern of a component loaded"))
+	}
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+type attrsCtx struct{}
+
+// ContextAttrs returns the attributes of a context to add to the logs, e.g. the ID of a request.
+type ContextAttrs func(ctx context.Context) []slog.Attr
+
+// WithAttrs creates a new context with the attributes added to the ones of the context. They are
+// added to the logs of the context by the ContextHandler.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	parent := AttrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsCtx{}, merged)
+}
+
+// AttrsFromContext returns the attributes added to the context with WithAttrs.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsCtx{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler is a slog.Handler adding the attributes of the context to the records, i.e. the
+// ones added with WithAttrs and the ones returned by its ContextAttrs. As the attributes of the
+// records, they are qualified by the groups of the handler.
+type ContextHandler struct {
+	handler slog.Handler
+	attrs   []ContextAttrs
+}
+
+// NewContextHandler creates a ContextHandler passing the records to the handler.
+func NewContextHandler(handler slog.Handler, attrs ...ContextAttrs) *ContextHandler {
+	return &ContextHandler{handler: handler, attrs: attrs}
+}
+
+// Enabled reports whether the handler handles the records at the level.
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle adds the attributes of the context to the record and passes it to the handler.
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := AttrsFromContext(ctx)
+	for _, fn := range h.attrs {
+		attrs = append(attrs[:len(attrs):len(attrs)], fn(ctx)...)
+	}
+	if len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a ContextHandler whose handler has the attributes.
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{handler: h.handler.WithAttrs(attrs), attrs: h.attrs}
+}
+
+// WithGroup returns a ContextHandler whose handler has the group.
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &ContextHandler{handler: h.handler.WithGroup(name), attrs: h.attrs}
+}
+
+// Flush flushes the handler.
+func (h *ContextHandler) Flush() error {
+	return Flush(h.handler)
+}
@@ -1,6 +1,7 @@
 package log_test
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"fmt"
@@ -9,7 +10,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/creasty/defaults"
 	"github.com/shangkuei/gap/log"
+	"github.com/shangkuei/gap/toml"
 	"github.com/spf13/afero"
 )
 
@@ -19,6 +22,12 @@ var consoleTOML embed.FS
 //go:embed testdata/file.toml
 var fileTOML embed.FS
 
+//go:embed testdata/json.toml
+var jsonTOML embed.FS
+
+//go:embed testdata/sinks.toml
+var sinksTOML embed.FS
+
 type embedFS embed.FS
 
 func (e *embedFS) Create(name string) (afero.File, error) {
@@ -167,7 +176,11 @@ func ExampleLogger_console() {
 		}
 		return attr
 	}
-	logger := log.Logger(config)
+	logger, closer, err := log.New(config)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
 
 	logger.Info("Hello, World!")
 
@@ -189,8 +202,12 @@ func ExampleLogger_file() {
 		}
 		return attr
 	}
-	logger := log.Logger(config)
 	defer os.Remove("file.log")
+	logger, closer, err := log.New(config)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
 
 	logger.Info("Hello, World!")
 
@@ -203,3 +220,212 @@ func ExampleLogger_file() {
 	// Output:
 	// level=INFO msg="Hello, World!"
 }
+
+func ExampleLogger_json() {
+	config, err := log.ConfigurationFromViper(log.ViperConfiguration{
+		ConfigFile: "testdata/json.toml",
+		FileSystem: (*embedFS)(&jsonTOML),
+	})
+	if err != nil {
+		panic(err)
+	}
+	config.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+		if attr.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return attr
+	}
+	logger, closer, err := log.New(config)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
+
+	logger.Info("Hello, World!", "count", 1)
+
+	// Output:
+	// {"level":"INFO","msg":"Hello, World!","count":1}
+}
+
+func ExampleLogger_sinks() {
+	config, err := log.ConfigurationFromViper(log.ViperConfiguration{
+		ConfigFile: "testdata/sinks.toml",
+		FileSystem: (*embedFS)(&sinksTOML),
+	})
+	if err != nil {
+		panic(err)
+	}
+	config.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+		if attr.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return attr
+	}
+	logger, closer, err := log.New(config)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
+	logger = logger.With("service", "gap").WithGroup("request")
+
+	logger.Info("Hello, World!", "id", 1)
+	logger.Debug("Hello, Debug!", "id", 2)
+
+	// Output:
+	// INF Hello, World! service=gap request.id=1
+	// {"level":"INFO","msg":"Hello, World!","service":"gap","request":{"id":1}}
+	// {"level":"DEBUG","msg":"Hello, Debug!","service":"gap","request":{"id":2}}
+}
+
+func ExampleWithAttrs() {
+	config, err := log.ConfigurationFromViper(log.ViperConfiguration{
+		ConfigFile: "testdata/json.toml",
+		FileSystem: (*embedFS)(&jsonTOML),
+	})
+	if err != nil {
+		panic(err)
+	}
+	config.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+		if attr.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return attr
+	}
+	type userCtx struct{}
+	config.ContextAttrs = []log.ContextAttrs{func(ctx context.Context) []slog.Attr {
+		if user, ok := ctx.Value(userCtx{}).(string); ok {
+			return []slog.Attr{slog.String("user", user)}
+		}
+		return nil
+	}}
+	logger, closer, err := log.New(config)
+	if err != nil {
+		panic(err)
+	}
+	defer closer.Close()
+
+	ctx := log.WithAttrs(context.Background(), slog.String("request", "42"))
+	ctx = context.WithValue(ctx, userCtx{}, "gopher")
+	logger.InfoContext(ctx, "Hello, World!")
+	logger.Info("Hello, World!")
+
+	// Output:
+	// {"level":"INFO","msg":"Hello, World!","request":"42","user":"gopher"}
+	// {"level":"INFO","msg":"Hello, World!"}
+}
+
+func ExampleConfiguration() {
+	var config log.Configuration
+	if err := defaults.Set(&config); err != nil {
+		panic(err)
+	}
+	err := toml.Encode(os.Stdout, config, func(opt *toml.EncodeOption) {
+		opt.Documented = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// # Type of the logger.
+	// # One of: console, file, syslog, journald
+	// # Default: console
+	// type = 'console'
+	// # Minimum level of the logs.
+	// # One of: trace, debug, info, warn, error, fatal
+	// # Default: info
+	// level = 'info'
+	// # Format of the logs: text is colored for the console and logfmt for the file.
+	// # One of: text, json, logfmt
+	// # Default: text
+	// format = 'text'
+	// # Path of the log file.
+	// file = ''
+	// # Permission of the log file.
+	// # Default: 0640
+	// permission = '0640'
+	// # Truncate the log file when it is opened.
+	// # Default: true
+	// truncate = true
+	// # Maximum size in megabytes of the log file before it is rotated, 0 to never rotate it by size.
+	// maxsize = 0
+	// # Duration after which the log file is rotated, 0 to never rotate it after a duration.
+	// interval = '0s'
+	// # Maximum number of days to keep the rotated log files, 0 to keep them.
+	// maxage = 0
+	// # Maximum number of rotated log files to keep, 0 to keep them.
+	// maxbackups = 0
+	// # Compress the rotated log files with gzip.
+	// compress = false
+	// # Name the rotated log files after the local time instead of UTC.
+	// localtime = false
+	// # Standard stream of the console logger.
+	// # One of: stderr, stdout
+	// # Default: stderr
+	// handler = 'stderr'
+	// # Time format of the console logger, named after the layouts of the time package.
+	// # One of: Layout, RubyDate, RFC822Z, RFC1123Z, RFC3339, Kitchen, DateTime, TimeOnly
+	// # Default: Kitchen
+	// time = 'Kitchen'
+	// # Disable the colors of the console logger.
+	// nocolor = false
+	// # Add the position in the source code to the logs.
+	// # Default: true
+	// source = true
+	//
+	// [syslog]
+	// # Network of the syslog server: unixgram or unix for a local socket, udp or tcp.
+	// # One of: unixgram, unix, udp, tcp
+	// # Default: unixgram
+	// network = 'unixgram'
+	// # Address of the syslog server, a socket path or a host:port.
+	// # Default: /dev/log
+	// address = '/dev/log'
+	// # Framing of the messages over unix and tcp: octet counting or newline, which escapes the newlines of the messages.
+	// # One of: octet, newline
+	// # Default: octet
+	// framing = 'octet'
+	// # Facility of the logs.
+	// # One of: kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp, local0, local1, local2, local3, local4, local5, local6, local7
+	// # Default: user
+	// facility = 'user'
+	// # Name of the application, the name of the executable by default.
+	// appname = ''
+	// # ID of the structured data holding the attributes of the logs.
+	// # Default: attrs@32473
+	// sdid = 'attrs@32473'
+	//
+	// [journald]
+	// # Path of the native socket of journald.
+	// # Default: /run/systemd/journal/socket
+	// socket = '/run/systemd/journal/socket'
+	// # Syslog identifier of the logs, the name of the executable by default.
+	// identifier = ''
+	//
+	// [async]
+	// # Number of logs queued to be written asynchronously, 0 to write them synchronously.
+	// queue = 0
+	// # Policy when the queue is full: block, dropnewest or dropoldest.
+	// # One of: block, dropnewest, dropoldest
+	// # Default: block
+	// overflow = 'block'
+	// # Maximum duration to wait for the queued logs to be written when the logger is closed, 0 to wait for all of them.
+	// # Default: 5s
+	// closetimeout = '5s'
+	//
+	// [sampling]
+	// # Interval of the sampling of the logs by level, 0 to disable it.
+	// interval = '0s'
+	// # Number of logs of each level logged per interval before sampling.
+	// # Default: 100
+	// first = 100
+	// # Log every Mth log of each level after the first ones per interval, 0 to drop them.
+	// # Default: 100
+	// thereafter = 100
+	// # Maximum level of the sampled logs.
+	// # One of: trace, debug, info, warn, error, fatal
+	// # Default: info
+	// level = 'info'
+	// # Window in which the duplicates of a log are suppressed and counted, 0 to disable it.
+	// dedup = '0s'
+}
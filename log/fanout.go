@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// FanoutHandler is a slog.Handler dispatching the records to several handlers, each one
+// filtering them by its own level.
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// NewFanoutHandler creates a FanoutHandler dispatching the records to the handlers.
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	return &FanoutHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers handles the records at the level.
+func (h *FanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle dispatches the record to the handlers enabled at its level. It returns the errors of
+// all the handlers, so that a failing handler doesn't prevent the others from handling it.
+func (h *FanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a FanoutHandler whose handlers all have the attributes.
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return NewFanoutHandler(handlers...)
+}
+
+// WithGroup returns a FanoutHandler whose handlers all have the group.
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return NewFanoutHandler(handlers...)
+}
+
+// Flush flushes all the handlers.
+func (h *FanoutHandler) Flush() error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if err := Flush(handler); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+)
+
+// field is an attribute of a record whose key is qualified by its groups joined with dots.
+type field struct {
+	key   string
+	value slog.Value
+}
+
+// fieldHandler is the base of the handlers writing the attributes of the records as flat fields,
+// e.g. the structured data of syslog.
+type fieldHandler struct {
+	opts   slog.HandlerOptions
+	groups []string
+	fields []field
+	write  func(record slog.Record, fields []field) error
+}
+
+func (h *fieldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minimum := slog.LevelInfo
+	if h.opts.Level != nil {
+		minimum = h.opts.Level.Level()
+	}
+	return level >= minimum
+}
+
+func (h *fieldHandler) Handle(_ context.Context, record slog.Record) error {
+	fields := h.fields[:len(h.fields):len(h.fields)]
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = h.appendField(fields, h.groups, attr)
+		return true
+	})
+	return h.write(record, fields)
+}
+
+func (h *fieldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *h
+	derived.fields = h.fields[:len(h.fields):len(h.fields)]
+	for _, attr := range attrs {
+		derived.fields = h.appendField(derived.fields, h.groups, attr)
+	}
+	return &derived
+}
+
+func (h *fieldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := *h
+	derived.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &derived
+}
+
+// appendField appends the attribute, or the attributes of a group, replaced by ReplaceAttr.
+func (h *fieldHandler) appendField(fields []field, groups []string, attr slog.Attr) []field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, member := range attr.Value.Group() {
+			fields = h.appendField(fields, groups, member)
+		}
+		return fields
+	}
+	if h.opts.ReplaceAttr != nil {
+		attr = h.opts.ReplaceAttr(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+	if attr.Key == "" {
+		return fields
+	}
+	key := attr.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+	return append(fields, field{key: key, value: attr.Value})
+}
+
+// builtin returns the value of a built-in attribute of the records, e.g. the message, replaced by
+// ReplaceAttr as slog's handlers do, and whether it is kept.
+func (h *fieldHandler) builtin(attr slog.Attr) (slog.Value, bool) {
+	if h.opts.ReplaceAttr != nil {
+		attr = h.opts.ReplaceAttr(nil, attr)
+	}
+	return attr.Value.Resolve(), attr.Key != ""
+}
+
+// message returns the message of the record replaced by ReplaceAttr, and whether it is kept.
+func (h *fieldHandler) message(record slog.Record) (string, bool) {
+	value, ok := h.builtin(slog.String(slog.MessageKey, record.Message))
+	return value.String(), ok
+}
+
+// source returns the source of the record replaced by ReplaceAttr if AddSource is set, and whether
+// it is kept. The source is nil if ReplaceAttr replaced it by another value, returned as text.
+func (h *fieldHandler) source(record slog.Record) (*slog.Source, string, bool) {
+	if !h.opts.AddSource || record.PC == 0 {
+		return nil, "", false
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	value, ok := h.builtin(slog.Any(slog.SourceKey, &slog.Source{
+		Function: frame.Function,
+		File:     frame.File,
+		Line:     frame.Line,
+	}))
+	if !ok {
+		return nil, "", false
+	}
+	if source, isSource := value.Any().(*slog.Source); isSource {
+		return source, "", true
+	}
+	return nil, value.String(), true
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JournaldConfiguration is the configuration of a journald sink.
+type JournaldConfiguration struct {
+	Socket     string `toml:"socket" mapstructure:"socket" default:"/run/systemd/journal/socket" comment:"Path of the native socket of journald."`
+	Identifier string `toml:"identifier" mapstructure:"identifier" comment:"Syslog identifier of the logs, the name of the executable by default."`
+}
+
+// JournaldHandler is a slog.Handler sending the records to journald with its native protocol. The
+// attributes are sent as fields named after their uppercase keys, with the characters not
+// allowed replaced by underscores and the names of the fields of the handler, e.g. MESSAGE,
+// prefixed with X_, and the levels are mapped to the priorities like the
+// severities of the SyslogHandler. A record must fit in a datagram.
+type JournaldHandler struct {
+	fieldHandler
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournaldHandler creates a JournaldHandler connected to the socket of the configuration.
+func NewJournaldHandler(config JournaldConfiguration, opts *slog.HandlerOptions) (*JournaldHandler, error) {
+	identifier := config.Identifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: config.Socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journald::%w", err)
+	}
+
+	h := &JournaldHandler{conn: conn}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.write = func(record slog.Record, fields []field) error {
+		var b bytes.Buffer
+		if message, ok := h.message(record); ok {
+			journaldField(&b, "MESSAGE", message)
+		}
+		journaldField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(record.Level)))
+		journaldField(&b, "SYSLOG_IDENTIFIER", identifier)
+		if source, text, ok := h.source(record); ok {
+			if source != nil {
+				journaldField(&b, "CODE_FILE", source.File)
+				journaldField(&b, "CODE_LINE", strconv.Itoa(source.Line))
+				journaldField(&b, "CODE_FUNC", source.Function)
+			} else {
+				journaldField(&b, journaldName(slog.SourceKey), text)
+			}
+		}
+		for _, f := range fields {
+			name := journaldName(f.key)
+			if journaldReserved[name] {
+				name = "X_" + name
+			}
+			journaldField(&b, name, f.value.String())
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.conn == nil {
+			return fmt.Errorf("journald::%w", net.ErrClosed)
+		}
+		_, err := h.conn.Write(b.Bytes())
+		return err
+	}
+	return h, nil
+}
+
+// Close closes the connection to journald.
+func (h *JournaldHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// journaldReserved are the names of the fields written by the JournaldHandler itself.
+var journaldReserved = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+	"SOURCE":            true,
+}
+
+// journaldField writes the field, in the binary format if the value has several lines.
+func journaldField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteString("=" + value + "\n")
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value + "\n")
+}
+
+// journaldName returns the name of the field of the key, i.e. uppercase letters, digits and
+// underscores, not starting with an underscore or a digit and at most 64 characters long.
+func journaldName(key string) string {
+	b := []byte(strings.ToUpper(key))
+	for i, c := range b {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	name := string(b)
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
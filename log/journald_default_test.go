@@ -0,0 +1,199 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+// listenUnixgram listens on a datagram socket in a short temporary directory, as the length of
+// the socket paths is limited.
+func listenUnixgram(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "gaplog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// parseJournald parses the fields of a message of the native protocol of journald.
+func parseJournald(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		line := bytes.IndexByte(data, '\n')
+		if line < 0 {
+			t.Fatal(helper.Message(t, "unterminated field", string(data)))
+		}
+		if equal := bytes.IndexByte(data[:line], '='); equal >= 0 {
+			fields[string(data[:equal])] = string(data[equal+1 : line])
+			data = data[line+1:]
+			continue
+		}
+		name, size := string(data[:line]), binary.LittleEndian.Uint64(data[line+1:line+9])
+		fields[name] = string(data[line+9 : line+9+int(size)])
+		data = data[line+9+int(size)+1:]
+	}
+	return fields
+}
+
+func TestJournaldHandler(t *testing.T) {
+	conn, path := listenUnixgram(t)
+	config := defaultConfig
+	config.Type, config.Level = "journald", "trace"
+	config.Journald.Socket, config.Journald.Identifier = path, "gap"
+	logger, closer, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	logger.With("user", "gopher").WithGroup("request").Warn("multiple\nlines", "user-agent", "go", "2fa", true)
+	logger.Info("reserved", "message", "attribute", "priority", 1)
+	logger.Log(context.Background(), LevelTrace, "trace")
+
+	buf := make([]byte, 64<<10)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := parseJournald(t, buf[:n])
+	if diff, ok := helper.Equal(fields["CODE_FILE"] != "" && fields["CODE_LINE"] != "", true); !ok {
+		t.Error(helper.Message(t, "source not sent", diff))
+	}
+	delete(fields, "CODE_FILE")
+	delete(fields, "CODE_LINE")
+	delete(fields, "CODE_FUNC")
+	want := map[string]string{
+		"MESSAGE":            "multiple\nlines",
+		"PRIORITY":           "4",
+		"SYSLOG_IDENTIFIER":  "gap",
+		"USER":               "gopher",
+		"REQUEST_USER_AGENT": "go",
+		"REQUEST_2FA":        "true",
+	}
+	if diff, ok := helper.Equal(fields, want); !ok {
+		t.Error(helper.Message(t, "unexpected fields", diff))
+	}
+
+	n, err = conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields = parseJournald(t, buf[:n])
+	for name, value := range map[string]string{"MESSAGE": "reserved", "PRIORITY": "6", "X_MESSAGE": "attribute", "X_PRIORITY": "1"} {
+		if diff, ok := helper.Equal(fields[name], value); !ok {
+			t.Error(helper.Message(t, "unexpected field "+name, diff))
+		}
+	}
+
+	n, err = conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(parseJournald(t, buf[:n])["PRIORITY"], strconv.Itoa(severityDebug)); !ok {
+		t.Error(helper.Message(t, "unexpected priority", diff))
+	}
+}
+
+func TestJournaldHandlerErrors(t *testing.T) {
+	config := defaultConfig.Journald
+	config.Socket = filepath.Join(t.TempDir(), "missing")
+	if _, err := NewJournaldHandler(config, nil); err == nil {
+		t.Error(helper.Message(t, "handler created without a socket"))
+	}
+
+	_, path := listenUnixgram(t)
+	config.Socket = path
+	handler, err := NewJournaldHandler(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.Handle(context.Background(), slog.Record{}); err == nil {
+		t.Error(helper.Message(t, "record sent after close"))
+	}
+}
+
+func TestJournaldHandlerReplaceAttr(t *testing.T) {
+	conn, path := listenUnixgram(t)
+	config := defaultConfig.Journald
+	config.Socket, config.Identifier = path, "gap"
+	handler, err := NewJournaldHandler(config, &slog.HandlerOptions{AddSource: true, ReplaceAttr: replaceBuiltins})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+	logger := slog.New(handler)
+	logger.Info("hello")
+	logger.Info("dropped")
+
+	buf := make([]byte, 64<<10)
+	for _, want := range []map[string]string{
+		{"MESSAGE": "HELLO", "PRIORITY": "6", "SYSLOG_IDENTIFIER": "gap", "SOURCE": "journald_default_test.go"},
+		{"PRIORITY": "6", "SYSLOG_IDENTIFIER": "gap", "SOURCE": "journald_default_test.go"},
+	} {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, ok := helper.Equal(parseJournald(t, buf[:n]), want); !ok {
+			t.Error(helper.Message(t, "unexpected fields", diff))
+		}
+	}
+}
+
+func TestJournaldName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "user.id", want: "USER_ID"},
+		{key: "_hidden", want: "X_HIDDEN"},
+		{key: "1st", want: "X1ST"},
+		{key: "", want: "X"},
+	}
+	for _, tt := range tests {
+		if diff, ok := helper.Equal(journaldName(tt.key), tt.want); !ok {
+			t.Error(helper.Message(t, "unexpected name", diff))
+		}
+	}
+}
+
+func TestSyslogHandlerUnixgram(t *testing.T) {
+	conn, path := listenUnixgram(t)
+	want := logSyslog(newTestSyslog(t, "unixgram", path, "octet"))
+	var messages []string
+	buf := make([]byte, 64<<10)
+	for range want {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, string(buf[:n]))
+	}
+	matchSyslog(t, messages, want)
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+const (
+	// LevelTrace is the level of the logs more verbose than debug.
+	LevelTrace = slog.Level(-8)
+	// LevelFatal is the level of the logs after which the program exits.
+	LevelFatal = slog.Level(12)
+)
+
+const (
+	ansiReset     = "\033[0m"
+	ansiBrightRed = "\033[91m"
+)
+
+var (
+	// levelNames names the custom levels in the text and JSON logs.
+	levelNames = map[slog.Level]string{
+		LevelTrace: "TRACE",
+		LevelFatal: "FATAL",
+	}
+
+	// consoleLevelNames names the custom levels in the console logs, like the levels of tint.
+	consoleLevelNames = map[slog.Level]string{
+		LevelTrace: "TRC",
+		LevelFatal: "FTL",
+	}
+
+	// exit exits the program after a fatal log.
+	exit = os.Exit
+)
+
+// Flusher is implemented by the handlers buffering the logs.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush flushes the handler if it buffers the logs.
+func Flush(handler slog.Handler) error {
+	if flusher, ok := handler.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Trace logs at LevelTrace with the default logger.
+func Trace(msg string, args ...any) {
+	slog.Default().Log(context.Background(), LevelTrace, msg, args...)
+}
+
+// TraceContext logs at LevelTrace with the default logger and the context.
+func TraceContext(ctx context.Context, msg string, args ...any) {
+	slog.Default().Log(ctx, LevelTrace, msg, args...)
+}
+
+// Fatal logs at LevelFatal with the default logger, flushes its handler and exits with status 1.
+func Fatal(msg string, args ...any) {
+	FatalContext(context.Background(), msg, args...)
+}
+
+// FatalContext logs at LevelFatal with the default logger and the context, flushes its handler
+// and exits with status 1.
+func FatalContext(ctx context.Context, msg string, args ...any) {
+	logger := slog.Default()
+	logger.Log(ctx, LevelFatal, msg, args...)
+	_ = Flush(logger.Handler())
+	exit(1)
+}
+
+// replaceLevel names the custom levels after the user function replaced the attribute.
+func replaceLevel(names map[slog.Level]string, color bool, replace func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, attr slog.Attr) slog.Attr {
+		if replace != nil {
+			attr = replace(groups, attr)
+		}
+		if attr.Key != slog.LevelKey || len(groups) != 0 || attr.Value.Kind() != slog.KindAny {
+			return attr
+		}
+		level, ok := attr.Value.Any().(slog.Level)
+		if !ok {
+			return attr
+		}
+		name, ok := names[level]
+		if !ok {
+			return attr
+		}
+		if color && level >= slog.LevelError {
+			name = ansiBrightRed + name + ansiReset
+		}
+		return slog.String(attr.Key, name)
+	}
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/lmittmann/tint"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+func dropTime(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return attr
+}
+
+func TestLevelNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(*bytes.Buffer) slog.Handler
+		want    string
+	}{
+		{
+			name: "text",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return slog.NewTextHandler(buf, &slog.HandlerOptions{
+					Level:       LevelTrace,
+					ReplaceAttr: replaceLevel(levelNames, false, dropTime),
+				})
+			},
+			want: "level=TRACE msg=trace\nlevel=DEBUG msg=debug\nlevel=FATAL msg=fatal\n",
+		},
+		{
+			name: "json",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return slog.NewJSONHandler(buf, &slog.HandlerOptions{
+					Level:       LevelTrace,
+					ReplaceAttr: replaceLevel(levelNames, false, dropTime),
+				})
+			},
+			want: `{"level":"TRACE","msg":"trace"}` + "\n" + `{"level":"DEBUG","msg":"debug"}` + "\n" + `{"level":"FATAL","msg":"fatal"}` + "\n",
+		},
+		{
+			name: "console",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return tint.NewHandler(buf, &tint.Options{
+					Level:       LevelTrace,
+					ReplaceAttr: replaceLevel(consoleLevelNames, false, dropTime),
+					NoColor:     true,
+				})
+			},
+			want: "TRC trace\nDBG debug\nFTL fatal\n",
+		},
+		{
+			name: "console with colors",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return tint.NewHandler(buf, &tint.Options{
+					Level:       LevelTrace,
+					ReplaceAttr: replaceLevel(consoleLevelNames, true, dropTime),
+				})
+			},
+			want: "TRC trace\nDBG debug\n\033[91mFTL\033[0m fatal\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(tt.handler(&buf))
+			logger.Log(context.Background(), LevelTrace, "trace")
+			logger.Debug("debug")
+			logger.Log(context.Background(), LevelFatal, "fatal")
+			if diff, ok := helper.Equal(buf.String(), tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected logs", diff))
+			}
+		})
+	}
+}
+
+// flushRecorder is a handler recording whether it was flushed.
+type flushRecorder struct {
+	slog.Handler
+	flushed bool
+}
+
+func (h *flushRecorder) Flush() error {
+	h.flushed = true
+	return nil
+}
+
+func TestFatal(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := &flushRecorder{Handler: slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: replaceLevel(levelNames, false, dropTime),
+	})}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(NewFanoutHandler(recorder)))
+	defer slog.SetDefault(defaultLogger)
+
+	var code int
+	defaultExit := exit
+	exit = func(c int) { code = c }
+	defer func() { exit = defaultExit }()
+
+	Trace("trace")
+	Fatal("fatal", "reason", "test")
+
+	if diff, ok := helper.Equal(buf.String(), "level=FATAL msg=fatal reason=test\n"); !ok {
+		t.Error(helper.Message(t, "unexpected logs", diff))
+	}
+	if diff, ok := helper.Equal(recorder.flushed, true); !ok {
+		t.Error(helper.Message(t, "handler not flushed", diff))
+	}
+	if diff, ok := helper.Equal(code, 1); !ok {
+		t.Error(helper.Message(t, "unexpected exit code", diff))
+	}
+}
@@ -0,0 +1,395 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultLevels are the levels of the default logger set by the package.
+var DefaultLevels = NewLevels()
+
+// Levels are the levels of the sinks of a logger, which can be changed while it is logging, and
+// the levels of its components overriding them. A logger is bound to Levels by setting them in
+// its Configuration.
+type Levels struct {
+	mu         sync.Mutex
+	sinks      []levelSink
+	toggled    bool
+	components map[string]slog.Level
+
+	// snapshot is the immutable copy of the components read by the handlers, published whenever
+	// they change so that logging doesn't take the lock.
+	snapshot atomic.Pointer[componentSnapshot]
+}
+
+// componentSnapshot holds the levels of the components by name and by pattern, the most specific
+// pattern first, and caches the levels of the names matched against the patterns.
+type componentSnapshot struct {
+	names    map[string]slog.Level
+	patterns []componentPattern
+	lowest   slog.Level
+	cache    sync.Map
+	cached   atomic.Int32
+}
+
+type componentPattern struct {
+	pattern string
+	level   slog.Level
+}
+
+// componentLevel is the cached level of a component, if matched.
+type componentLevel struct {
+	level slog.Level
+	found bool
+}
+
+// maxCachedComponents bounds the number of component names cached by a snapshot.
+const maxCachedComponents = 1024
+
+type levelSink struct {
+	name  string
+	level slog.Level
+	value *slog.LevelVar
+}
+
+// NewLevels creates Levels bound to no sink.
+func NewLevels() *Levels {
+	return &Levels{}
+}
+
+// levelSinks creates the levels of the sinks, each one starting at its configured level.
+func levelSinks(sinks []SinkConfiguration) []levelSink {
+	bound := make([]levelSink, len(sinks))
+	for i, sink := range sinks {
+		level := logLevelMaping[sink.Level]
+		bound[i] = levelSink{name: sinkName(sink), level: level, value: &slog.LevelVar{}}
+		bound[i].value.Set(level)
+	}
+	return bound
+}
+
+// bind replaces the sinks and the levels of the components.
+func (l *Levels) bind(sinks []levelSink, components map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks, l.toggled = sinks, false
+	l.components = componentLevels(components)
+	l.publish()
+}
+
+// validatePattern reports whether the pattern of the components is malformed.
+func validatePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("levels::invalid component %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// validateComponents reports the malformed patterns of the levels of the components.
+func validateComponents(components map[string]string) error {
+	patterns := make([]string, 0, len(components))
+	for pattern := range components {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	var errs []error
+	for _, pattern := range patterns {
+		if err := validatePattern(pattern); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// componentLevels parses the levels of the components of the configuration.
+func componentLevels(components map[string]string) map[string]slog.Level {
+	levels := make(map[string]slog.Level, len(components))
+	for pattern, level := range components {
+		levels[pattern] = logLevelMaping[level]
+	}
+	return levels
+}
+
+// sinkName returns the name of the sink, which is its type if not set.
+func sinkName(sink SinkConfiguration) string {
+	if sink.Name != "" {
+		return sink.Name
+	}
+	return sink.Type
+}
+
+// Set sets the level of all the sinks.
+func (l *Levels) Set(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, sink := range l.sinks {
+		sink.value.Set(level)
+	}
+}
+
+// SetSink sets the level of the sinks with the name.
+func (l *Levels) SetSink(name string, level slog.Level) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	found := false
+	for _, sink := range l.sinks {
+		if sink.name == name {
+			sink.value.Set(level)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("levels::unknown sink %q", name)
+	}
+	return nil
+}
+
+// Reset sets the sinks back to their configured levels.
+func (l *Levels) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, sink := range l.sinks {
+		sink.value.Set(sink.level)
+	}
+	l.toggled = false
+}
+
+// Get returns the current levels of the sinks by name.
+func (l *Levels) Get() map[string]slog.Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	levels := make(map[string]slog.Level, len(l.sinks))
+	for _, sink := range l.sinks {
+		levels[sink.name] = sink.value.Level()
+	}
+	return levels
+}
+
+// SetComponent overrides the level of the sinks for the components matching the pattern, which is
+// a component name or a pattern of path.Match, e.g. db.*. The most specific pattern applies to a
+// component, i.e. its name or else the longest pattern. The override applies to all the sinks,
+// including the ones at a higher level.
+func (l *Levels) SetComponent(pattern string, level slog.Level) error {
+	if err := validatePattern(pattern); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.components == nil {
+		l.components = make(map[string]slog.Level)
+	}
+	l.components[pattern] = level
+	l.publish()
+	return nil
+}
+
+// RemoveComponent removes the level of the components matching the pattern.
+func (l *Levels) RemoveComponent(pattern string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.components, pattern)
+	l.publish()
+}
+
+// Components returns the levels of the components by pattern.
+func (l *Levels) Components() map[string]slog.Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	components := make(map[string]slog.Level, len(l.components))
+	for pattern, level := range l.components {
+		components[pattern] = level
+	}
+	return components
+}
+
+// publish publishes the snapshot of the components. It must be called with the lock held.
+func (l *Levels) publish() {
+	snapshot := &componentSnapshot{names: make(map[string]slog.Level)}
+	for pattern, level := range l.components {
+		if len(snapshot.names) == 0 || level < snapshot.lowest {
+			snapshot.lowest = level
+		}
+		snapshot.names[pattern] = level
+		snapshot.patterns = append(snapshot.patterns, componentPattern{pattern: pattern, level: level})
+	}
+	sort.Slice(snapshot.patterns, func(i, j int) bool {
+		a, b := snapshot.patterns[i].pattern, snapshot.patterns[j].pattern
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	l.snapshot.Store(snapshot)
+}
+
+// lowestComponent returns the lowest level of the components if any.
+func (l *Levels) lowestComponent() (slog.Level, bool) {
+	if l == nil {
+		return 0, false
+	}
+	snapshot := l.snapshot.Load()
+	if snapshot == nil || len(snapshot.names) == 0 {
+		return 0, false
+	}
+	return snapshot.lowest, true
+}
+
+// component returns the level of the component if overridden.
+func (l *Levels) component(name string) (slog.Level, bool) {
+	if l == nil {
+		return 0, false
+	}
+	snapshot := l.snapshot.Load()
+	if snapshot == nil || len(snapshot.names) == 0 {
+		return 0, false
+	}
+	if level, ok := snapshot.names[name]; ok {
+		return level, true
+	}
+	if cached, ok := snapshot.cache.Load(name); ok {
+		return cached.(componentLevel).level, cached.(componentLevel).found
+	}
+
+	var matched componentLevel
+	for _, pattern := range snapshot.patterns {
+		if ok, _ := path.Match(pattern.pattern, name); ok {
+			matched = componentLevel{level: pattern.level, found: true}
+			break
+		}
+	}
+	if snapshot.cached.Load() < maxCachedComponents {
+		if _, loaded := snapshot.cache.LoadOrStore(name, matched); !loaded {
+			snapshot.cached.Add(1)
+		}
+	}
+	return matched.level, matched.found
+}
+
+// apply sets the levels of the sinks found in the configuration, which also become their
+// configured levels, and replaces the levels of the components.
+func (l *Levels) apply(config Configuration) {
+	sinks := config.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfiguration{config.SinkConfiguration}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, configured := range sinks {
+		level := logLevelMaping[configured.Level]
+		for i, sink := range l.sinks {
+			if sink.name == sinkName(configured) {
+				l.sinks[i].level = level
+				sink.value.Set(level)
+			}
+		}
+	}
+	l.toggled = false
+	l.components = componentLevels(config.Components)
+	l.publish()
+}
+
+// Toggle sets all the sinks to the level, or back to their configured levels if they were
+// toggled already.
+func (l *Levels) Toggle(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, sink := range l.sinks {
+		if l.toggled {
+			sink.value.Set(sink.level)
+		} else {
+			sink.value.Set(level)
+		}
+	}
+	l.toggled = !l.toggled
+}
+
+// ToggleOnSignal toggles the sinks between debug and their configured levels whenever one of the
+// signals is received, e.g. syscall.SIGUSR1, until the context is done.
+func (l *Levels) ToggleOnSignal(ctx context.Context, signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				l.Toggle(slog.LevelDebug)
+			}
+		}
+	}()
+}
+
+// ServeHTTP serves the levels as a JSON object of the sink names to the level names. A PUT or a
+// POST request sets the level of the form to all the sinks, or to the sink of the form if any.
+// The handler should only be served on a local or an authenticated admin listener.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		level, err := ParseLevel(r.FormValue("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if sink := r.FormValue("sink"); sink != "" {
+			if err := l.SetSink(sink, level); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		} else {
+			l.Set(level)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	levels := make(map[string]string)
+	for name, level := range l.Get() {
+		levels[name] = LevelName(level)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levels)
+}
+
+// ParseLevel parses the name of a level of the configuration, e.g. trace, or of slog, e.g.
+// DEBUG+2.
+func ParseLevel(name string) (slog.Level, error) {
+	if level, ok := logLevelMaping[strings.ToLower(name)]; ok {
+		return level, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("levels::unknown level %q", name)
+	}
+	return level, nil
+}
+
+// LevelName returns the name of the level, including the custom levels.
+func LevelName(level slog.Level) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return level.String()
+}
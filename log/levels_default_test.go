@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+func TestLevelsToggleOnSignal(t *testing.T) {
+	levels, values := newTestLevels(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	levels.ToggleOnSignal(ctx, syscall.SIGUSR1)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for values[0].Level() != slog.LevelDebug && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if diff, ok := helper.Equal(values[0].Level(), slog.LevelDebug); !ok {
+		t.Error(helper.Message(t, "level not toggled", diff))
+	}
+}
@@ -0,0 +1,181 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+// bindLevels binds the levels to the sinks and returns their values.
+func bindLevels(levels *Levels, sinks []SinkConfiguration) []*slog.LevelVar {
+	bound := levelSinks(sinks)
+	levels.bind(bound, nil)
+	values := make([]*slog.LevelVar, len(bound))
+	for i, sink := range bound {
+		values[i] = sink.value
+	}
+	return values
+}
+
+func newTestLevels(t *testing.T) (*Levels, []*slog.LevelVar) {
+	t.Helper()
+
+	levels := NewLevels()
+	values := bindLevels(levels, []SinkConfiguration{
+		{Type: "console", Level: "info"},
+		{Name: "audit", Type: "file", Level: "warn"},
+	})
+	return levels, values
+}
+
+func TestLevels(t *testing.T) {
+	levels, values := newTestLevels(t)
+	current := func() []slog.Level {
+		return []slog.Level{values[0].Level(), values[1].Level()}
+	}
+
+	levels.Set(LevelTrace)
+	if diff, ok := helper.Equal(current(), []slog.Level{LevelTrace, LevelTrace}); !ok {
+		t.Error(helper.Message(t, "unexpected levels after set", diff))
+	}
+	if err := levels.SetSink("audit", slog.LevelError); err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(current(), []slog.Level{LevelTrace, slog.LevelError}); !ok {
+		t.Error(helper.Message(t, "unexpected levels after set sink", diff))
+	}
+	if err := levels.SetSink("unknown", slog.LevelError); err == nil {
+		t.Error(helper.Message(t, "unknown sink set"))
+	}
+	levels.Reset()
+	if diff, ok := helper.Equal(current(), []slog.Level{slog.LevelInfo, slog.LevelWarn}); !ok {
+		t.Error(helper.Message(t, "unexpected levels after reset", diff))
+	}
+
+	levels.Toggle(slog.LevelDebug)
+	if diff, ok := helper.Equal(current(), []slog.Level{slog.LevelDebug, slog.LevelDebug}); !ok {
+		t.Error(helper.Message(t, "unexpected levels after toggle", diff))
+	}
+	levels.Toggle(slog.LevelDebug)
+	if diff, ok := helper.Equal(current(), []slog.Level{slog.LevelInfo, slog.LevelWarn}); !ok {
+		t.Error(helper.Message(t, "unexpected levels after toggle back", diff))
+	}
+}
+
+func TestLevelsLogger(t *testing.T) {
+	var buf bytes.Buffer
+	levels := NewLevels()
+	sink := SinkConfiguration{Type: "console", Level: "info", Format: "json"}
+	handler := formatHandler(Configuration{ReplaceAttr: dropTime}, sink, &buf, bindLevels(levels, []SinkConfiguration{sink})[0])
+	logger := slog.New(handler)
+
+	logger.Debug("hidden")
+	levels.Set(slog.LevelDebug)
+	logger.Debug("shown")
+
+	if diff, ok := helper.Equal(buf.String(), `{"level":"DEBUG","msg":"shown"}`+"\n"); !ok {
+		t.Error(helper.Message(t, "unexpected logs", diff))
+	}
+}
+
+func TestLevelsServeHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		form   url.Values
+		code   int
+		want   map[string]string
+	}{
+		{name: "get", method: http.MethodGet, code: http.StatusOK, want: map[string]string{"console": "INFO", "audit": "WARN"}},
+		{name: "set", method: http.MethodPut, form: url.Values{"level": {"trace"}}, code: http.StatusOK, want: map[string]string{"console": "TRACE", "audit": "TRACE"}},
+		{name: "set sink", method: http.MethodPost, form: url.Values{"level": {"DEBUG+2"}, "sink": {"audit"}}, code: http.StatusOK, want: map[string]string{"console": "INFO", "audit": "DEBUG+2"}},
+		{name: "unknown level", method: http.MethodPut, form: url.Values{"level": {"verbose"}}, code: http.StatusBadRequest},
+		{name: "unknown sink", method: http.MethodPut, form: url.Values{"level": {"debug"}, "sink": {"unknown"}}, code: http.StatusNotFound},
+		{name: "unsupported method", method: http.MethodDelete, code: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, _ := newTestLevels(t)
+			request := httptest.NewRequest(tt.method, "/log/levels", strings.NewReader(tt.form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			recorder := httptest.NewRecorder()
+			levels.ServeHTTP(recorder, request)
+
+			if diff, ok := helper.Equal(recorder.Code, tt.code); !ok {
+				t.Fatal(helper.Message(t, "unexpected status", diff, recorder.Body.String()))
+			}
+			if tt.want == nil {
+				return
+			}
+			var got map[string]string
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected levels", diff))
+			}
+		})
+	}
+}
+
+func TestWatchLevels(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gaplog.toml")
+	if err := os.WriteFile(file, []byte("level = \"info\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	levels := NewLevels()
+	config, err := ConfigurationFromViper(ViperConfiguration{ConfigFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := bindLevels(levels, []SinkConfiguration{config.SinkConfiguration})[0]
+	if err := WatchLevels(ViperConfiguration{ConfigFile: file}, levels); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, []byte("level = \"debug\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for value.Level() != slog.LevelDebug && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if diff, ok := helper.Equal(value.Level(), slog.LevelDebug); !ok {
+		t.Error(helper.Message(t, "level not reloaded", diff))
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{name: "trace", want: LevelTrace},
+		{name: "FATAL", want: LevelFatal},
+		{name: "warn", want: slog.LevelWarn},
+		{name: "INFO-2", want: slog.LevelInfo - 2},
+		{name: "verbose", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if diff, ok := helper.Equal(err != nil, tt.wantErr); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(got, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected level", diff))
+			}
+		})
+	}
+}
@@ -1,44 +1,87 @@
 package log
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
-	"os"
+	"syscall"
 	"time"
 
 	"github.com/creasty/defaults"
 	"github.com/go-playground/validator/v10"
 	"github.com/lmittmann/tint"
+	"github.com/spf13/viper"
 )
 
 type Configuration struct {
-	Type        string                                          `toml:"type" mapstructure:"type" default:"console" validate:"oneof=console file"`
-	Level       string                                          `toml:"level" mapstructure:"level" default:"info" validate:"oneof=trace debug info warn error"`
-	AddSource   bool                                            `toml:"source" mapstructure:"source" default:"true"`
-	ReplaceAttr func(groups []string, attr slog.Attr) slog.Attr `toml:"-" mapstructure:"-"`
-	File        FileConfiguration                               `toml:",omitempty,squash" mapstructure:",squash"`
-	Console     ConsoleConfiguration                            `toml:",omitempty,squash" mapstructure:",squash"`
+	SinkConfiguration `toml:",squash" mapstructure:",squash"`
+	AddSource         bool                                            `toml:"source" mapstructure:"source" default:"true" comment:"Add the position in the source code to the logs."`
+	ReplaceAttr       func(groups []string, attr slog.Attr) slog.Attr `toml:"-" mapstructure:"-"`
+	Redact            []RedactionRule                                 `toml:"redact,omitempty" mapstructure:"redact" validate:"dive" comment:"Rules redacting the attributes of the logs before ReplaceAttr."`
+	Sampling          SamplingConfiguration                           `toml:"sampling" mapstructure:"sampling"`
+	Sinks             []SinkConfiguration                             `toml:"sinks,omitempty" mapstructure:"sinks" validate:"dive" comment:"Sinks receiving the logs simultaneously, in place of the sink above."`
+	Components        map[string]string                               `toml:"levels,omitempty" mapstructure:"levels" validate:"dive,keys,required,endkeys,oneof=trace debug info warn error fatal" comment:"Levels of the components overriding the levels of all the sinks, keyed by component name or pattern, e.g. db.*."`
+	Levels            *Levels                                         `toml:"-" mapstructure:"-"`
+	ContextAttrs      []ContextAttrs                                  `toml:"-" mapstructure:"-"`
+}
+
+// SinkConfiguration is the configuration of a destination of the logs.
+type SinkConfiguration struct {
+	Name     string                `toml:"name,omitempty" mapstructure:"name" comment:"Name of the sink to change its level at runtime, its type by default."`
+	Type     string                `toml:"type" mapstructure:"type" default:"console" validate:"oneof=console file syslog journald" comment:"Type of the logger."`
+	Level    string                `toml:"level" mapstructure:"level" default:"info" validate:"oneof=trace debug info warn error fatal" comment:"Minimum level of the logs."`
+	Format   string                `toml:"format" mapstructure:"format" default:"text" validate:"oneof=text json logfmt" comment:"Format of the logs: text is colored for the console and logfmt for the file."`
+	File     FileConfiguration     `toml:",omitempty,squash" mapstructure:",squash"`
+	Console  ConsoleConfiguration  `toml:",omitempty,squash" mapstructure:",squash"`
+	Syslog   SyslogConfiguration   `toml:"syslog" mapstructure:"syslog"`
+	Journald JournaldConfiguration `toml:"journald" mapstructure:"journald"`
+	Async    AsyncConfiguration    `toml:"async" mapstructure:"async"`
+}
+
+// SamplingConfiguration is the configuration of the sampling and the deduplication of the logs.
+type SamplingConfiguration struct {
+	Interval   time.Duration `toml:"interval" mapstructure:"interval" validate:"gte=0" comment:"Interval of the sampling of the logs by level, 0 to disable it."`
+	First      int           `toml:"first" mapstructure:"first" default:"100" validate:"gte=0" comment:"Number of logs of each level logged per interval before sampling."`
+	Thereafter int           `toml:"thereafter" mapstructure:"thereafter" default:"100" validate:"gte=0" comment:"Log every Mth log of each level after the first ones per interval, 0 to drop them."`
+	Level      string        `toml:"level" mapstructure:"level" default:"info" validate:"omitempty,oneof=trace debug info warn error fatal" comment:"Maximum level of the sampled logs."`
+	Window     time.Duration `toml:"dedup" mapstructure:"dedup" validate:"gte=0" comment:"Window in which the duplicates of a log are suppressed and counted, 0 to disable it."`
 }
 
 type FileConfiguration struct {
-	File       string      `toml:"file" mapstructure:"file" validate:"isdefault|filepath"`
-	Permission fs.FileMode `toml:"permission" mapstructure:"permission" default:"0640"`
-	Truncate   bool        `toml:"truncate" mapstructure:"truncate" default:"true"`
+	File       string        `toml:"file" mapstructure:"file" validate:"isdefault|filepath" comment:"Path of the log file."`
+	Permission fs.FileMode   `toml:"permission" mapstructure:"permission" default:"0640" comment:"Permission of the log file."`
+	Truncate   bool          `toml:"truncate" mapstructure:"truncate" default:"true" comment:"Truncate the log file when it is opened."`
+	MaxSize    int           `toml:"maxsize" mapstructure:"maxsize" validate:"gte=0" comment:"Maximum size in megabytes of the log file before it is rotated, 0 to never rotate it by size."`
+	Interval   time.Duration `toml:"interval" mapstructure:"interval" validate:"gte=0" comment:"Duration after which the log file is rotated, 0 to never rotate it after a duration."`
+	MaxAge     int           `toml:"maxage" mapstructure:"maxage" validate:"gte=0" comment:"Maximum number of days to keep the rotated log files, 0 to keep them."`
+	MaxBackups int           `toml:"maxbackups" mapstructure:"maxbackups" validate:"gte=0" comment:"Maximum number of rotated log files to keep, 0 to keep them."`
+	Compress   bool          `toml:"compress" mapstructure:"compress" comment:"Compress the rotated log files with gzip."`
+	LocalTime  bool          `toml:"localtime" mapstructure:"localtime" comment:"Name the rotated log files after the local time instead of UTC."`
+}
+
+// AsyncConfiguration is the configuration of the asynchronous writes of a sink.
+type AsyncConfiguration struct {
+	Queue        int           `toml:"queue" mapstructure:"queue" validate:"gte=0" comment:"Number of logs queued to be written asynchronously, 0 to write them synchronously."`
+	Overflow     string        `toml:"overflow" mapstructure:"overflow" default:"block" validate:"omitempty,oneof=block dropnewest dropoldest" comment:"Policy when the queue is full: block, dropnewest or dropoldest."`
+	CloseTimeout time.Duration `toml:"closetimeout" mapstructure:"closetimeout" default:"5s" validate:"gte=0" comment:"Maximum duration to wait for the queued logs to be written when the logger is closed, 0 to wait for all of them."`
 }
 
 type ConsoleConfiguration struct {
-	Handler    string `toml:"handler" mapstructure:"handler" default:"stderr" validate:"oneof=stderr stdout"`
-	TimeFormat string `toml:"time" mapstructure:"time" default:"Kitchen" validate:"oneof=Layout RubyDate RFC822Z RFC1123Z RFC3339 Kitchen DateTime TimeOnly"`
-	NoColor    bool   `toml:"nocolor" mapstructure:"nocolor"`
+	Handler    string `toml:"handler" mapstructure:"handler" default:"stderr" validate:"omitempty,oneof=stderr stdout" comment:"Standard stream of the console logger."`
+	TimeFormat string `toml:"time" mapstructure:"time" default:"Kitchen" validate:"omitempty,oneof=Layout RubyDate RFC822Z RFC1123Z RFC3339 Kitchen DateTime TimeOnly" comment:"Time format of the console logger, named after the layouts of the time package."`
+	NoColor    bool   `toml:"nocolor" mapstructure:"nocolor" comment:"Disable the colors of the console logger."`
 }
 
 var (
 	logLevelMaping = map[string]slog.Level{
+		"trace": LevelTrace,
 		"debug": slog.LevelDebug,
 		"info":  slog.LevelInfo,
 		"warn":  slog.LevelWarn,
 		"error": slog.LevelError,
+		"fatal": LevelFatal,
 	}
 
 	timefmtMappping = map[string]string{
@@ -62,50 +105,228 @@ func init() {
 	if err := validator.New().Struct(defaultConfig); err != nil {
 		panic(err)
 	}
+}
 
-	var v ViperConfiguration
+// Setup sets the default logger of slog from the configuration loaded using viper, which is
+// looked up as gaplog in the working directory by default. The default configuration is used if
+// no configuration file is found, but an error is returned if the ConfigFile is missing. The
+// returned io.Closer closes the files of the logger, and is a Dropper.
+func Setup(v ViperConfiguration) (io.Closer, error) {
 	if err := defaults.Set(&v); err != nil {
-		panic(err)
+		return nil, err
+	}
+	config, err := ConfigurationFromViper(v)
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return nil, err
+	}
+	config.Levels = DefaultLevels
+	logger, closer, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return closer, nil
+}
+
+// New creates a logger writing to the sinks of the configuration, or to the sink of the
+// configuration itself if it has none. The levels of the sinks can be changed at runtime through
+// the Levels of the configuration. The attributes of the contexts are added to the logs, see
+// WithAttrs. The returned io.Closer handles the queued logs and closes the files of the logger,
+// and is a Dropper.
+func New(config Configuration) (*slog.Logger, io.Closer, error) {
+	if err := validator.New().Struct(config); err != nil {
+		return nil, nil, err
+	}
+	if err := validateComponents(config.Components); err != nil {
+		return nil, nil, err
+	}
+	replace, err := Redact(config.Redact, config.ReplaceAttr)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.ReplaceAttr = replace
+
+	sinks := config.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfiguration{config.SinkConfiguration}
+	}
+	levels := config.Levels
+	if levels == nil {
+		levels = NewLevels()
 	}
-	config, _ := ConfigurationFromViper(v)
-	slog.SetDefault(Logger(config))
+	bound := levelSinks(sinks)
+	handlers := make([]slog.Handler, len(sinks))
+	var closer closers
+	for i, sink := range sinks {
+		handler, closers, err := sinkHandler(config, sink, bound[i].value, levels)
+		closer = append(closer, closers...)
+		if err != nil {
+			return nil, nil, errors.Join(err, closer.Close())
+		}
+		handlers[i] = handler
+	}
+	levels.bind(bound, config.Components)
+
+	handler := handlers[0]
+	if len(handlers) > 1 {
+		handler = NewFanoutHandler(handlers...)
+	}
+	if sampling := config.Sampling; sampling.Interval > 0 || sampling.Window > 0 {
+		sampler := NewSamplingHandler(handler, func(opt *SamplingOption) {
+			opt.Interval = sampling.Interval
+			opt.First = sampling.First
+			opt.Thereafter = sampling.Thereafter
+			opt.Level = logLevelMaping[sampling.Level]
+			opt.Window = sampling.Window
+		})
+		handler, closer = sampler, append(closer, sampler)
+	}
+	return slog.New(NewContextHandler(handler, config.ContextAttrs...)), closer, nil
 }
 
+// Logger creates a logger like New, but panics if it fails.
+//
+// Deprecated: Use New, which returns the errors and the io.Closer of the files of the logger.
 func Logger(config Configuration) *slog.Logger {
-	var handler slog.Handler
-	switch config.Type {
-	case "console":
-		var timeFormat string
-		if config.Console.TimeFormat != "" {
-			timeFormat = timefmtMappping[config.Console.TimeFormat]
+	logger, _, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+	return logger
+}
+
+// closers closes the resources of a logger in the reverse order of their creation.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var errs []error
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dropper reports the number of records dropped by the overflow policies of the asynchronous
+// sinks, e.g. closer.(log.Dropper).Dropped() with the io.Closer returned by New.
+type Dropper interface {
+	Dropped() int64
+}
+
+// Dropped returns the number of records dropped by the asynchronous sinks.
+func (c closers) Dropped() int64 {
+	var dropped int64
+	for _, closer := range c {
+		if dropper, ok := closer.(Dropper); ok {
+			dropped += dropper.Dropped()
 		}
-		var writer io.Writer
-		if config.Console.Handler == "stdout" {
-			writer = stdout()
-		} else {
-			writer = stderr()
+	}
+	return dropped
+}
+
+func sinkHandler(config Configuration, sink SinkConfiguration, level slog.Leveler, levels *Levels) (slog.Handler, closers, error) {
+	var (
+		handler slog.Handler
+		closer  closers
+	)
+	options := &slog.HandlerOptions{AddSource: config.AddSource, Level: level, ReplaceAttr: config.ReplaceAttr}
+	switch sink.Type {
+	case "syslog":
+		syslog, err := NewSyslogHandler(sink.Syslog, options)
+		if err != nil {
+			return nil, nil, err
 		}
-		handler = tint.NewHandler(writer, &tint.Options{
-			AddSource:   config.AddSource,
-			Level:       logLevelMaping[config.Level],
-			ReplaceAttr: config.ReplaceAttr,
-			TimeFormat:  timeFormat,
-			NoColor:     config.Console.NoColor,
+		handler, closer = syslog, closers{syslog}
+	case "journald":
+		journald, err := NewJournaldHandler(sink.Journald, options)
+		if err != nil {
+			return nil, nil, err
+		}
+		handler, closer = journald, closers{journald}
+	default:
+		writer, writerCloser, err := sinkWriter(sink)
+		if err != nil {
+			return nil, nil, err
+		}
+		handler = &syncHandler{Handler: formatHandler(config, sink, writer, level), writer: writer}
+		closer = writerCloser
+	}
+	if sink.Async.Queue > 0 {
+		async := NewAsyncHandler(handler, func(opt *AsyncOption) {
+			opt.QueueSize = sink.Async.Queue
+			opt.Overflow = overflowMapping[sink.Async.Overflow]
+			opt.CloseTimeout = sink.Async.CloseTimeout
 		})
-	case "file":
-		flag := os.O_CREATE | os.O_APPEND | os.O_WRONLY
-		if config.File.Truncate {
-			flag |= os.O_TRUNC
+		handler, closer = async, append(closer, async)
+	}
+	return NewComponentHandler(handler, level, levels), closer, nil
+}
+
+func sinkWriter(sink SinkConfiguration) (io.Writer, closers, error) {
+	switch sink.Type {
+	case "console":
+		if sink.Console.Handler == "stdout" {
+			return stdout(), nil, nil
 		}
-		file, err := os.OpenFile(config.File.File, flag, config.File.Permission)
+		return stderr(), nil, nil
+	case "file":
+		file, err := NewRotatingFile(sink.File)
 		if err != nil {
-			panic(err)
+			return nil, nil, err
 		}
-		handler = slog.NewTextHandler(file, &slog.HandlerOptions{
+		return file, closers{file}, nil
+	}
+	return nil, nil, fmt.Errorf("log::unknown type %q", sink.Type)
+}
+
+func formatHandler(config Configuration, sink SinkConfiguration, writer io.Writer, level slog.Leveler) slog.Handler {
+	options := &slog.HandlerOptions{
+		AddSource:   config.AddSource,
+		Level:       level,
+		ReplaceAttr: replaceLevel(levelNames, false, config.ReplaceAttr),
+	}
+	switch {
+	case sink.Format == "json":
+		return slog.NewJSONHandler(writer, options)
+	case sink.Format == "logfmt" || sink.Type == "file":
+		return slog.NewTextHandler(writer, options)
+	default:
+		var timeFormat string
+		if sink.Console.TimeFormat != "" {
+			timeFormat = timefmtMappping[sink.Console.TimeFormat]
+		}
+		return tint.NewHandler(writer, &tint.Options{
 			AddSource:   config.AddSource,
-			Level:       logLevelMaping[config.Level],
-			ReplaceAttr: config.ReplaceAttr,
+			Level:       level,
+			ReplaceAttr: replaceLevel(consoleLevelNames, !sink.Console.NoColor, config.ReplaceAttr),
+			TimeFormat:  timeFormat,
+			NoColor:     sink.Console.NoColor,
 		})
 	}
-	return slog.New(handler)
+}
+
+// syncHandler is a handler syncing its writer when flushed.
+type syncHandler struct {
+	slog.Handler
+	writer io.Writer
+}
+
+// Flush commits the logs written to a file to the storage.
+func (h *syncHandler) Flush() error {
+	if syncer, ok := h.writer.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTSUP) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *syncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syncHandler{Handler: h.Handler.WithAttrs(attrs), writer: h.writer}
+}
+
+func (h *syncHandler) WithGroup(name string) slog.Handler {
+	return &syncHandler{Handler: h.Handler.WithGroup(name), writer: h.writer}
 }
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+	"github.com/shangkuei/gap/toml"
+)
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	fileSink := func(path string) SinkConfiguration {
+		sink := defaultConfig.SinkConfiguration
+		sink.Type, sink.File.File = "file", path
+		return sink
+	}
+
+	tests := []struct {
+		name    string
+		config  func(Configuration) Configuration
+		wantErr bool
+	}{
+		{name: "default", config: func(config Configuration) Configuration { return config }},
+		{name: "file", config: func(config Configuration) Configuration {
+			config.SinkConfiguration = fileSink(filepath.Join(dir, "file.log"))
+			return config
+		}},
+		{name: "without defaults", config: func(Configuration) Configuration {
+			return Configuration{SinkConfiguration: SinkConfiguration{
+				Type: "file", Level: "info", Format: "logfmt", File: FileConfiguration{File: filepath.Join(dir, "plain.log")},
+			}}
+		}},
+		{name: "unknown facility", config: func(config Configuration) Configuration {
+			config.Type, config.Syslog.Facility = "syslog", "unknown"
+			return config
+		}, wantErr: true},
+		{name: "unknown type", config: func(config Configuration) Configuration {
+			config.Type = "unknown"
+			return config
+		}, wantErr: true},
+		{name: "unknown level", config: func(config Configuration) Configuration {
+			config.Level = "verbose"
+			return config
+		}, wantErr: true},
+		{name: "missing directory", config: func(config Configuration) Configuration {
+			config.SinkConfiguration = fileSink(filepath.Join(dir, "missing", "file.log"))
+			return config
+		}, wantErr: true},
+		{name: "failing sink after a file", config: func(config Configuration) Configuration {
+			config.Sinks = []SinkConfiguration{
+				fileSink(filepath.Join(dir, "first.log")),
+				fileSink(filepath.Join(dir, "missing", "second.log")),
+			}
+			return config
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels := NewLevels()
+			config := tt.config(defaultConfig)
+			config.Levels = levels
+			logger, closer, err := New(config)
+			if diff, ok := helper.Equal(err != nil, tt.wantErr); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			if err != nil {
+				if diff, ok := helper.Equal(len(levels.Get()), 0); !ok {
+					t.Error(helper.Message(t, "levels bound to a failed logger", diff))
+				}
+				return
+			}
+			if err := closer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if logger == nil {
+				t.Error(helper.Message(t, "no logger"))
+			}
+		})
+	}
+}
+
+func TestNewClose(t *testing.T) {
+	config := defaultConfig
+	config.Type, config.File.File = "file", filepath.Join(t.TempDir(), "file.log")
+	config.Async.Queue = 16
+	logger, closer, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("queued")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(config.File.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(len(data) > 0, true); !ok {
+		t.Error(helper.Message(t, "queued log not written", diff))
+	}
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "closed", 0)
+	if err := logger.Handler().Handle(context.Background(), record); !errors.Is(err, ErrHandlerClosed) {
+		t.Error(helper.Message(t, "log handled after close"))
+	}
+}
+
+func TestNewDropped(t *testing.T) {
+	config := defaultConfig
+	config.Async.Queue, config.Async.Overflow = 1, "dropnewest"
+	_, closer, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropper, ok := closer.(Dropper)
+	if !ok {
+		t.Fatal(helper.Message(t, "closer is not a Dropper"))
+	}
+	if diff, ok := helper.Equal(dropper.Dropped(), int64(0)); !ok {
+		t.Error(helper.Message(t, "unexpected dropped count", diff))
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	inner := newGateHandler()
+	async := NewAsyncHandler(inner, func(opt *AsyncOption) {
+		opt.QueueSize, opt.Overflow = 1, OverflowDropNewest
+	})
+	logger := slog.New(async)
+	logger.Info("handled")
+	<-inner.started
+	logger.Info("queued")
+	logger.Info("dropped")
+	dropper = closers{async}
+	if diff, ok := helper.Equal(dropper.Dropped(), int64(1)); !ok {
+		t.Error(helper.Message(t, "unexpected dropped count", diff))
+	}
+	close(inner.gate)
+	if err := async.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.toml")
+	if err := os.WriteFile(invalid, []byte("level = \"verbose\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	malformed := filepath.Join(dir, "malformed.toml")
+	if err := os.WriteFile(malformed, []byte("level = \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	valid := filepath.Join(dir, "valid.toml")
+	if err := os.WriteFile(valid, []byte("type = \"file\"\nfile = \""+filepath.Join(dir, "file.log")+"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		v       ViperConfiguration
+		wantErr bool
+	}{
+		{name: "valid", v: ViperConfiguration{ConfigFile: valid}},
+		{name: "no configuration file", v: ViperConfiguration{ConfigPath: []string{dir}}},
+		{name: "missing configuration file", v: ViperConfiguration{ConfigFile: filepath.Join(dir, "missing.toml")}, wantErr: true},
+		{name: "invalid configuration", v: ViperConfiguration{ConfigFile: invalid}, wantErr: true},
+		{name: "malformed configuration", v: ViperConfiguration{ConfigFile: malformed}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultLogger := slog.Default()
+			defer slog.SetDefault(defaultLogger)
+
+			closer, err := Setup(tt.v)
+			if diff, ok := helper.Equal(err != nil, tt.wantErr); !ok {
+				t.Fatal(helper.Message(t, "unexpected error", diff))
+			}
+			if err != nil {
+				if slog.Default() != defaultLogger {
+					t.Error(helper.Message(t, "default logger set after an error"))
+				}
+				return
+			}
+			defer closer.Close()
+			if _, ok := slog.Default().Handler().(*ContextHandler); !ok {
+				t.Error(helper.Message(t, "default logger not set"))
+			}
+		})
+	}
+}
+
+func TestConfigurationDocumented(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gaplog.toml")
+	err := toml.EncodeFile(file, defaultConfig, func(opt *toml.EncodeOption) {
+		opt.Documented = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ConfigurationFromViper(ViperConfiguration{ConfigFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(config, defaultConfig); !ok {
+		t.Error(helper.Message(t, "unexpected configuration of the sample", diff))
+	}
+}
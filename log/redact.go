@@ -0,0 +1,127 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RedactionRule is the configuration of the redaction of the attributes of the logs.
+type RedactionRule struct {
+	Keys    []string `toml:"keys,omitempty" mapstructure:"keys" comment:"Keys of the redacted attributes, or patterns of path.Match, matched against the key and the path of the groups joined with dots, e.g. *password or user.email."`
+	Values  []string `toml:"values,omitempty" mapstructure:"values" comment:"Regular expressions of the redacted parts of the values in text, e.g. of credit card numbers or bearer tokens."`
+	Action  string   `toml:"action" mapstructure:"action" default:"mask" validate:"oneof=mask hash" comment:"Redaction of the values: mask replaces them, hash replaces them by the beginning of their HMAC-SHA256 with the hash key."`
+	Mask    string   `toml:"mask" mapstructure:"mask" default:"[REDACTED]" comment:"Replacement of the masked values."`
+	HashKey string   `toml:"hashkey,omitempty" mapstructure:"hashkey" validate:"required_if=Action hash" comment:"Secret key of the HMAC of the hashed values, so that they can't be guessed from their hashes."`
+}
+
+// hashSize is the number of bytes of the HMAC kept in the hashed values.
+const hashSize = 16
+
+// redactor is a compiled redaction rule.
+type redactor struct {
+	keys    []string
+	values  []*regexp.Regexp
+	hashKey []byte
+	mask    string
+}
+
+// Redact compiles the redaction rules into a function for slog.HandlerOptions.ReplaceAttr,
+// redacting the attributes before passing them to the replace function if any. The keys are
+// matched case-insensitively.
+func Redact(rules []RedactionRule, replace func([]string, slog.Attr) slog.Attr) (func([]string, slog.Attr) slog.Attr, error) {
+	if len(rules) == 0 {
+		return replace, nil
+	}
+
+	redactors := make([]redactor, len(rules))
+	for i, rule := range rules {
+		r := redactor{mask: rule.Mask}
+		if rule.Action == "hash" {
+			if rule.HashKey == "" {
+				return nil, errors.New("redact::hash action without a hash key")
+			}
+			r.hashKey = []byte(rule.HashKey)
+		}
+		for _, key := range rule.Keys {
+			key = strings.ToLower(key)
+			if _, err := path.Match(key, ""); err != nil {
+				return nil, fmt.Errorf("redact::invalid key %q: %w", key, err)
+			}
+			r.keys = append(r.keys, key)
+		}
+		for _, value := range rule.Values {
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("redact::invalid value %q: %w", value, err)
+			}
+			r.values = append(r.values, re)
+		}
+		redactors[i] = r
+	}
+
+	return func(groups []string, attr slog.Attr) slog.Attr {
+		for _, r := range redactors {
+			attr = r.redact(groups, attr)
+		}
+		if replace != nil {
+			attr = replace(groups, attr)
+		}
+		return attr
+	}, nil
+}
+
+func (r redactor) redact(groups []string, attr slog.Attr) slog.Attr {
+	if r.matchKey(groups, attr.Key) {
+		return slog.String(attr.Key, r.replace(attr.Value.Resolve().String()))
+	}
+	if len(r.values) == 0 {
+		return attr
+	}
+	resolved := attr.Value.Resolve()
+	if resolved.Kind() == slog.KindGroup {
+		return attr
+	}
+	// The values of the other kinds are redacted in text, and kept as they are if not redacted.
+	original := resolved.String()
+	value := original
+	for _, re := range r.values {
+		value = re.ReplaceAllStringFunc(value, r.replace)
+	}
+	if value == original {
+		return attr
+	}
+	return slog.String(attr.Key, value)
+}
+
+func (r redactor) matchKey(groups []string, key string) bool {
+	if len(r.keys) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	qualified := strings.ToLower(strings.Join(append(groups[:len(groups):len(groups)], key), "."))
+	for _, pattern := range r.keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, qualified); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r redactor) replace(value string) string {
+	if r.hashKey == nil {
+		return r.mask
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(value))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:hashSize])
+}
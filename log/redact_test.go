@@ -0,0 +1,119 @@
+package log
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+const redactTOML = `
+[[redact]]
+keys = ["*password", "user.email", "Token"]
+
+[[redact]]
+values = ['\b(?:\d[ -]?){12,15}\d\b', 'Bearer \S+']
+action = "hash"
+hashkey = "secret"
+`
+
+func TestRedact(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gaplog.toml")
+	if err := os.WriteFile(file, []byte(redactTOML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ConfigurationFromViper(ViperConfiguration{ConfigFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RedactionRule{
+		{Keys: []string{"*password", "user.email", "Token"}, Action: "mask", Mask: "[REDACTED]"},
+		{Values: []string{`\b(?:\d[ -]?){12,15}\d\b`, `Bearer \S+`}, Action: "hash", Mask: "[REDACTED]", HashKey: "secret"},
+	}
+	if diff, ok := helper.Equal(config.Redact, want); !ok {
+		t.Fatal(helper.Message(t, "unexpected rules", diff))
+	}
+
+	replace, err := Redact(config.Redact, dropTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: replace}))
+	logger.Info("login",
+		"db_password", "hunter2",
+		"token", 42,
+		slog.Group("user", "email", "gopher@example.com", "name", "gopher"),
+		"email", "public@example.com",
+		"card", "4111 1111 1111 1111",
+		"header", "Bearer abc.def",
+		"account", 4111111111111111,
+		"error", errors.New("rejected Bearer abc.def"),
+		"count", 3,
+	)
+
+	line := "level=INFO msg=login db_password=[REDACTED] token=[REDACTED] user.email=[REDACTED] user.name=gopher" +
+		" email=public@example.com card=" + hash("secret", "4111 1111 1111 1111") + " header=" + hash("secret", "Bearer abc.def") +
+		" account=" + hash("secret", "4111111111111111") + " error=\"rejected " + hash("secret", "Bearer abc.def") + "\" count=3\n"
+	if diff, ok := helper.Equal(buf.String(), line); !ok {
+		t.Error(helper.Message(t, "unexpected log", diff))
+	}
+}
+
+func TestRedactInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule RedactionRule
+	}{
+		{name: "key", rule: RedactionRule{Keys: []string{"["}}},
+		{name: "value", rule: RedactionRule{Values: []string{"("}}},
+		{name: "hash key", rule: RedactionRule{Values: []string{"token"}, Action: "hash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Redact([]RedactionRule{tt.rule}, nil); err == nil {
+				t.Error(helper.Message(t, "invalid rule compiled"))
+			}
+			config := defaultConfig
+			config.Redact = []RedactionRule{tt.rule}
+			if config.Redact[0].Action == "" {
+				config.Redact[0].Action = "mask"
+			}
+			if _, _, err := New(config); err == nil {
+				t.Error(helper.Message(t, "logger created with an invalid rule"))
+			}
+		})
+	}
+}
+
+func hash(key, value string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:2*hashSize]
+}
+
+func TestRedactHashKey(t *testing.T) {
+	rule := RedactionRule{Keys: []string{"card"}, Action: "hash"}
+	var hashes []string
+	for _, key := range []string{"first", "second"} {
+		rule.HashKey = key
+		replace, err := Redact([]RedactionRule{rule}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, replace(nil, slog.String("card", "4111")).Value.String())
+	}
+	if diff, ok := helper.Equal(hashes, []string{hash("first", "4111"), hash("second", "4111")}); !ok {
+		t.Error(helper.Message(t, "unexpected hashes", diff))
+	}
+	if hashes[0] == hashes[1] {
+		t.Error(helper.Message(t, "hashes independent of the key"))
+	}
+}
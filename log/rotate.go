@@ -0,0 +1,355 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupTimeFormat is the layout of the time in the names of the rotated files.
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+	megabyte         = 1 << 20
+)
+
+// RotatingFile is a log file rotated when it grows over a maximum size or has been written for
+// an interval. The rotated files are renamed after the time of their rotation, e.g.
+// app-2006-01-02T15-04-05.000.log, or app-2006-01-02T15-04-05.000-1.log if the name is already
+// taken, then compressed with gzip and removed when they are too old or too many in the
+// background, so that the logs are not held up by it.
+type RotatingFile struct {
+	config FileConfiguration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	// mill holds a pending compression and clean up of the rotated files, done one at a time by
+	// the goroutine started with the file, and milling counts the pending ones.
+	mill    chan struct{}
+	milling sync.WaitGroup
+	millErr error
+}
+
+// NewRotatingFile opens the log file of the configuration for appending, or truncates it if
+// Truncate is set.
+func NewRotatingFile(config FileConfiguration) (*RotatingFile, error) {
+	r := &RotatingFile{config: config, now: time.Now, mill: make(chan struct{}, 1)}
+	if err := r.open(config.Truncate); err != nil {
+		return nil, err
+	}
+	go r.millRun()
+	return r, nil
+}
+
+// Write writes the log to the file, which is rotated first if the log would make it grow over
+// MaxSize, or if it has been opened for Interval.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && (r.oversized(len(p)) || r.expired()) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file regardless of its size.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotate()
+}
+
+// Sync commits the content of the file to the storage.
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.file.Sync()
+}
+
+// Close closes the file, and waits for the compression and the clean up of the rotated files,
+// returning their errors.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	close(r.mill)
+	r.milling.Wait()
+	return errors.Join(err, r.millErr)
+}
+
+// oversized reports whether writing n bytes would make the file grow over MaxSize.
+func (r *RotatingFile) oversized(n int) bool {
+	maxSize := int64(r.config.MaxSize) * megabyte
+	return maxSize > 0 && r.size+int64(n) > maxSize
+}
+
+// expired reports whether the file has been opened for Interval.
+func (r *RotatingFile) expired() bool {
+	return r.config.Interval > 0 && r.now().Sub(r.opened) >= r.config.Interval
+}
+
+func (r *RotatingFile) open(truncate bool) error {
+	flag := os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	if truncate {
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(r.config.File, flag, r.config.Permission)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size, r.opened = file, info.Size(), r.now()
+	return nil
+}
+
+// rotate renames the file after the current time, opens a new one and requests the compression
+// and the clean up of the rotated files.
+func (r *RotatingFile) rotate() error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+
+	backup := r.backupName(r.now())
+	if err := os.Rename(r.config.File, backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := r.open(true); err != nil {
+		return err
+	}
+
+	if r.config.Compress || r.config.MaxBackups > 0 || r.config.MaxAge > 0 {
+		// A pending request handles this rotation too, since it lists the rotated files.
+		r.milling.Add(1)
+		select {
+		case r.mill <- struct{}{}:
+		default:
+			r.milling.Done()
+		}
+	}
+	return nil
+}
+
+// millRun compresses and cleans up the rotated files on request, until the file is closed. The
+// errors are kept for Close.
+func (r *RotatingFile) millRun() {
+	for range r.mill {
+		err := r.cleanUp()
+		if r.config.Compress {
+			err = errors.Join(err, r.compress())
+		}
+		if err != nil {
+			r.millErr = errors.Join(r.millErr, err)
+		}
+		r.milling.Done()
+	}
+}
+
+// backupName returns the name of the file rotated at the time, with a counter suffix if a file
+// was already rotated with the same name, compressed or not.
+func (r *RotatingFile) backupName(t time.Time) string {
+	if !r.config.LocalTime {
+		t = t.UTC()
+	}
+	dir, prefix, ext := r.nameParts()
+	name := prefix + t.Format(backupTimeFormat)
+	for i := 0; ; i++ {
+		backup := filepath.Join(dir, name+ext)
+		if i > 0 {
+			backup = filepath.Join(dir, name+"-"+strconv.Itoa(i)+ext)
+		}
+		if !fileExists(backup) && !fileExists(backup+compressSuffix) {
+			return backup
+		}
+	}
+}
+
+// fileExists reports whether there is a file at the path.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// nameParts returns the directory, the prefix before the time and the extension of the names
+// of the rotated files.
+func (r *RotatingFile) nameParts() (string, string, string) {
+	dir, base := filepath.Split(r.config.File)
+	ext := filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+type backupFile struct {
+	path  string
+	time  time.Time
+	count int
+}
+
+// backups returns the rotated files, the most recent first.
+func (r *RotatingFile) backups() ([]backupFile, error) {
+	dir, prefix, ext := r.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	location := time.UTC
+	if r.config.LocalTime {
+		location = time.Local
+	}
+	var backups []backupFile
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), compressSuffix)
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		t, count, ok := parseBackupTime(name[len(prefix):len(name)-len(ext)], location)
+		if !ok {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, entry.Name()), time: t, count: count})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].time.Equal(backups[j].time) {
+			return backups[i].count > backups[j].count
+		}
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+// parseBackupTime parses the time and the optional counter suffix in the name of a rotated file.
+func parseBackupTime(value string, location *time.Location) (time.Time, int, bool) {
+	if len(value) < len(backupTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.ParseInLocation(backupTimeFormat, value[:len(backupTimeFormat)], location)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	suffix := value[len(backupTimeFormat):]
+	if suffix == "" {
+		return t, 0, true
+	}
+	count, err := strconv.Atoi(strings.TrimPrefix(suffix, "-"))
+	if err != nil || !strings.HasPrefix(suffix, "-") || count <= 0 {
+		return time.Time{}, 0, false
+	}
+	return t, count, true
+}
+
+// cleanUp removes the rotated files beyond MaxBackups or older than MaxAge days.
+func (r *RotatingFile) cleanUp() error {
+	if r.config.MaxBackups <= 0 && r.config.MaxAge <= 0 {
+		return nil
+	}
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := r.now().Add(-time.Duration(r.config.MaxAge) * 24 * time.Hour)
+	var errs []error
+	for i, backup := range backups {
+		tooMany := r.config.MaxBackups > 0 && i >= r.config.MaxBackups
+		tooOld := r.config.MaxAge > 0 && backup.time.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(backup.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compress compresses the rotated files which are not compressed yet.
+func (r *RotatingFile) compress() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, backup := range backups {
+		if strings.HasSuffix(backup.path, compressSuffix) {
+			continue
+		}
+		if err := compressFile(backup.path, r.config.Permission); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressFile compresses the file with gzip and removes it.
+func compressFile(path string, perm fs.FileMode) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(dst)
+	if _, err := io.Copy(writer, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("compress %s: %w", path, err)
+	}
+	if err := writer.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
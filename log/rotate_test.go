@@ -0,0 +1,305 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+// fakeClock is a clock advanced by the tests, which fires its timers when they are due.
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) timer {
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	timers := c.timers
+	c.timers = nil
+	for _, t := range timers {
+		switch {
+		case t.stopped:
+		case t.at.After(c.now):
+			c.timers = append(c.timers, t)
+		default:
+			t.stopped = true
+			t.f()
+		}
+	}
+}
+
+func newTestRotatingFile(t *testing.T, config FileConfiguration, clock *fakeClock) *RotatingFile {
+	t.Helper()
+
+	config.File = filepath.Join(t.TempDir(), "app.log")
+	config.Permission = 0o600
+	file, err := NewRotatingFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.now, file.opened = clock.Now, clock.Now()
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+// waitMill waits for the compression and the clean up of the rotated files.
+func waitMill(file *RotatingFile) {
+	file.milling.Wait()
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeMegabytes(t *testing.T, file *RotatingFile, b byte, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if _, err := file.Write(bytes.Repeat([]byte{b}, megabyte/2)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(bytes.Repeat([]byte{b}, megabyte/2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)}
+	file := newTestRotatingFile(t, FileConfiguration{MaxSize: 1}, clock)
+	dir := filepath.Dir(file.config.File)
+
+	writeMegabytes(t, file, 'a', 1)
+	if diff, ok := helper.Equal(listDir(t, dir), []string{"app.log"}); !ok {
+		t.Error(helper.Message(t, "rotated before the maximum size", diff))
+	}
+
+	if _, err := file.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"app-2024-01-02T03-04-05.006.log", "app.log"}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Fatal(helper.Message(t, "unexpected files", diff))
+	}
+	data, err := os.ReadFile(file.config.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(string(data), "b"); !ok {
+		t.Error(helper.Message(t, "unexpected content of the new file", diff))
+	}
+	info, err := os.Stat(filepath.Join(dir, want[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(info.Size(), int64(megabyte)); !ok {
+		t.Error(helper.Message(t, "unexpected size of the rotated file", diff))
+	}
+}
+
+func TestRotatingFileBackups(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	file := newTestRotatingFile(t, FileConfiguration{MaxBackups: 2, MaxAge: 3}, clock)
+	dir := filepath.Dir(file.config.File)
+
+	for i := 0; i < 4; i++ {
+		if _, err := file.Write([]byte("log\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := file.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		waitMill(file)
+		clock.Advance(time.Hour)
+	}
+	want := []string{"app-2024-01-01T02-00-00.000.log", "app-2024-01-01T03-00-00.000.log", "app.log"}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Error(helper.Message(t, "unexpected files beyond the maximum backups", diff))
+	}
+
+	clock.Advance(3 * 24 * time.Hour)
+	if err := file.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	waitMill(file)
+	want = []string{"app-2024-01-04T04-00-00.000.log", "app.log"}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Error(helper.Message(t, "unexpected files beyond the maximum age", diff))
+	}
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)}
+	file := newTestRotatingFile(t, FileConfiguration{Compress: true, LocalTime: true}, clock)
+	dir := filepath.Dir(file.config.File)
+
+	if _, err := file.Write([]byte("compressed log\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	waitMill(file)
+	want := []string{"app-2024-01-01T00-00-00.000.log.gz", "app.log"}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Fatal(helper.Message(t, "unexpected files", diff))
+	}
+
+	compressed, err := os.Open(filepath.Join(dir, want[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compressed.Close()
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(string(data), "compressed log\n"); !ok {
+		t.Error(helper.Message(t, "unexpected content of the compressed file", diff))
+	}
+
+	backups, err := file.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(len(backups) == 1 && backups[0].time.Equal(clock.now), true); !ok {
+		t.Error(helper.Message(t, "unexpected backups", diff))
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	file := newTestRotatingFile(t, FileConfiguration{Interval: time.Hour}, clock)
+	dir := filepath.Dir(file.config.File)
+
+	for _, advance := range []time.Duration{0, 59 * time.Minute, time.Minute, time.Hour} {
+		clock.Advance(advance)
+		if _, err := file.Write([]byte("log\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"app-2024-01-01T01-00-00.000.log", "app-2024-01-01T02-00-00.000.log", "app.log"}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Fatal(helper.Message(t, "unexpected files", diff))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, want[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(string(data), "log\nlog\n"); !ok {
+		t.Error(helper.Message(t, "unexpected content of the rotated file", diff))
+	}
+}
+
+func TestRotatingFileSameTime(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	file := newTestRotatingFile(t, FileConfiguration{Compress: true, MaxBackups: 3}, clock)
+	dir := filepath.Dir(file.config.File)
+
+	for i := 0; i < 4; i++ {
+		if _, err := file.Write([]byte{'a' + byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := file.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		waitMill(file)
+	}
+	want := []string{
+		"app-2024-01-01T00-00-00.000-1.log.gz",
+		"app-2024-01-01T00-00-00.000-2.log.gz",
+		"app-2024-01-01T00-00-00.000-3.log.gz",
+		"app.log",
+	}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Fatal(helper.Message(t, "unexpected files rotated at the same time", diff))
+	}
+
+	backups, err := file.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []int
+	for _, backup := range backups {
+		counts = append(counts, backup.count)
+	}
+	if diff, ok := helper.Equal(counts, []int{3, 2, 1}); !ok {
+		t.Error(helper.Message(t, "unexpected order of the backups", diff))
+	}
+}
+
+func TestRotatingFileClose(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	file := newTestRotatingFile(t, FileConfiguration{Compress: true}, clock)
+	dir := filepath.Dir(file.config.File)
+
+	for i := 0; i < 3; i++ {
+		if _, err := file.Write([]byte("log\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := file.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"app-2024-01-01T00-00-00.000.log.gz",
+		"app-2024-01-01T00-00-01.000.log.gz",
+		"app-2024-01-01T00-00-02.000.log.gz",
+		"app.log",
+	}
+	if diff, ok := helper.Equal(listDir(t, dir), want); !ok {
+		t.Error(helper.Message(t, "uncompressed files after closing", diff))
+	}
+	if err := file.Rotate(); err != os.ErrClosed {
+		t.Error(helper.Message(t, "rotated after closing", fmt.Sprint(err)))
+	}
+}
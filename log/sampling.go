@@ -0,0 +1,269 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepeatedKey is the key of the number of suppressed duplicates in the summary of a
+// SamplingHandler.
+const RepeatedKey = "repeated"
+
+// SamplingOption is a type for functional options for the NewSamplingHandler function.
+type SamplingOption struct {
+	// Interval is the interval of the sampling by level, which is disabled if it isn't positive.
+	Interval time.Duration
+	// First is the number of records of each level handled per interval before sampling.
+	First int
+	// Thereafter handles every Thereafter-th record of each level after the first ones per
+	// interval, or none if it isn't positive.
+	Thereafter int
+	// Level is the maximum level of the sampled records, so that errors are never dropped.
+	Level slog.Level
+	// Window suppresses the duplicates of a record handled within the window, which is disabled if
+	// it isn't positive.
+	Window time.Duration
+}
+
+// SamplingHandler is a slog.Handler sampling the records by level and suppressing their
+// duplicates within the window, i.e. records of the same level, message and attributes. The number
+// of suppressed duplicates of a record is reported by a summary record with a RepeatedKey
+// attribute when its window ends, or when the handler is flushed or closed.
+type SamplingHandler struct {
+	handler slog.Handler
+	prefix  string
+	state   *samplingState
+}
+
+type samplingState struct {
+	opt       SamplingOption
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func()) timer
+
+	mu     sync.Mutex
+	counts map[slog.Level]*sampleCount
+	dedups map[string]*dedupWindow
+}
+
+type sampleCount struct {
+	start time.Time
+	count int
+}
+
+// dedupWindow is the window of a record whose duplicates are suppressed.
+type dedupWindow struct {
+	start    time.Time
+	record   slog.Record
+	handler  slog.Handler
+	repeated int
+	timer    timer
+}
+
+// timer is the part of a time.Timer used to end the windows, so that the tests can fake it.
+type timer interface {
+	Stop() bool
+}
+
+// summary is the summary of the suppressed duplicates of a record to pass to its handler.
+type summary struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+// NewSamplingHandler creates a SamplingHandler passing the sampled records to the handler.
+func NewSamplingHandler(handler slog.Handler, opts ...func(*SamplingOption)) *SamplingHandler {
+	opt := SamplingOption{Level: slog.LevelInfo}
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	return &SamplingHandler{
+		handler: handler,
+		state: &samplingState{
+			opt: opt,
+			now: time.Now,
+			afterFunc: func(d time.Duration, f func()) timer {
+				return time.AfterFunc(d, f)
+			},
+			counts: make(map[slog.Level]*sampleCount),
+			dedups: make(map[string]*dedupWindow),
+		},
+	}
+}
+
+// Enabled reports whether the handler handles the records at the level.
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle passes the record to the handler unless it is a duplicate or it isn't sampled.
+func (h *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	s := h.state
+	s.mu.Lock()
+	now := s.now()
+
+	var summaries []summary
+	if s.opt.Window > 0 {
+		key := h.key(record)
+		if window, ok := s.dedups[key]; ok {
+			if now.Sub(window.start) < s.opt.Window {
+				window.repeated++
+				s.mu.Unlock()
+				return nil
+			}
+			summaries = s.end(key, window, summaries)
+		}
+		window := &dedupWindow{start: now, record: record.Clone(), handler: h.handler}
+		window.timer = s.afterFunc(s.opt.Window, func() { s.expire(key, window) })
+		s.dedups[key] = window
+	}
+
+	sampled := s.sample(record.Level, now)
+	s.mu.Unlock()
+
+	err := handleSummaries(ctx, summaries)
+	if !sampled {
+		return err
+	}
+	return errors.Join(err, h.handler.Handle(ctx, record))
+}
+
+// expire ends the window of the record of the key when its timer fires, unless it already ended.
+func (s *samplingState) expire(key string, window *dedupWindow) {
+	s.mu.Lock()
+	if s.dedups[key] != window {
+		s.mu.Unlock()
+		return
+	}
+	summaries := s.end(key, window, nil)
+	s.mu.Unlock()
+	_ = handleSummaries(context.Background(), summaries)
+}
+
+// end removes the window of the record of the key, and appends the summary of its suppressed
+// duplicates if any to the summaries.
+func (s *samplingState) end(key string, window *dedupWindow, summaries []summary) []summary {
+	window.timer.Stop()
+	delete(s.dedups, key)
+	return s.summarize(window, summaries)
+}
+
+// summarize appends the summary of the suppressed duplicates of the window if any to the
+// summaries, and resets their number.
+func (s *samplingState) summarize(window *dedupWindow, summaries []summary) []summary {
+	if window.repeated == 0 {
+		return summaries
+	}
+	record := slog.NewRecord(s.now(), window.record.Level, window.record.Message, window.record.PC)
+	window.record.Attrs(func(attr slog.Attr) bool {
+		record.AddAttrs(attr)
+		return true
+	})
+	record.AddAttrs(slog.Int(RepeatedKey, window.repeated))
+	window.repeated = 0
+	return append(summaries, summary{handler: window.handler, record: record})
+}
+
+// handleSummaries passes the summaries to their handlers. It is called without holding the lock
+// of the state, so that the handlers don't block the other records.
+func handleSummaries(ctx context.Context, summaries []summary) error {
+	var errs []error
+	for _, summary := range summaries {
+		if err := summary.handler.Handle(ctx, summary.record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sample reports whether the record of the level is sampled.
+func (s *samplingState) sample(level slog.Level, now time.Time) bool {
+	if s.opt.Interval <= 0 || level > s.opt.Level {
+		return true
+	}
+	count, ok := s.counts[level]
+	if !ok || now.Sub(count.start) >= s.opt.Interval {
+		count = &sampleCount{start: now}
+		s.counts[level] = count
+	}
+	count.count++
+	if count.count <= s.opt.First {
+		return true
+	}
+	return s.opt.Thereafter > 0 && (count.count-s.opt.First)%s.opt.Thereafter == 0
+}
+
+// key identifies the duplicates of the record.
+func (h *SamplingHandler) key(record slog.Record) string {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	b.WriteString(record.Level.String())
+	b.WriteByte(' ')
+	b.WriteString(record.Message)
+	record.Attrs(func(attr slog.Attr) bool {
+		b.WriteByte(' ')
+		b.WriteString(attr.String())
+		return true
+	})
+	return b.String()
+}
+
+// WithAttrs returns a SamplingHandler whose handler has the attributes, which shares the state of
+// the sampling.
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := h.prefix
+	for _, attr := range attrs {
+		prefix += attr.String() + " "
+	}
+	return &SamplingHandler{handler: h.handler.WithAttrs(attrs), prefix: prefix, state: h.state}
+}
+
+// WithGroup returns a SamplingHandler whose handler has the group, which shares the state of the
+// sampling.
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SamplingHandler{handler: h.handler.WithGroup(name), prefix: h.prefix + name + ". ", state: h.state}
+}
+
+// Flush handles the summaries of the suppressed duplicates if any and flushes the handler.
+func (h *SamplingHandler) Flush() error {
+	return errors.Join(h.state.flush(false), Flush(h.handler))
+}
+
+// Close handles the summaries of the suppressed duplicates if any and ends the windows, so that no
+// summary is handled afterwards.
+func (h *SamplingHandler) Close() error {
+	return h.state.flush(true)
+}
+
+// flush handles the summaries of the suppressed duplicates if any, in the order of their windows,
+// and ends the windows if end is set.
+func (s *samplingState) flush(end bool) error {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.dedups))
+	for key := range s.dedups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if start, other := s.dedups[keys[i]].start, s.dedups[keys[j]].start; !start.Equal(other) {
+			return start.Before(other)
+		}
+		return keys[i] < keys[j]
+	})
+	var summaries []summary
+	for _, key := range keys {
+		if end {
+			summaries = s.end(key, s.dedups[key], summaries)
+		} else {
+			summaries = s.summarize(s.dedups[key], summaries)
+		}
+	}
+	s.mu.Unlock()
+	return handleSummaries(context.Background(), summaries)
+}
@@ -0,0 +1,211 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+func newTestSamplingHandler(buf *bytes.Buffer, clock *fakeClock, opts ...func(*SamplingOption)) *SamplingHandler {
+	handler := NewSamplingHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level:       LevelTrace,
+		ReplaceAttr: dropTime,
+	}), opts...)
+	handler.state.now, handler.state.afterFunc = clock.Now, clock.AfterFunc
+	return handler
+}
+
+func TestSamplingHandlerSample(t *testing.T) {
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	logger := slog.New(newTestSamplingHandler(&buf, clock, func(opt *SamplingOption) {
+		opt.Interval = time.Second
+		opt.First = 2
+		opt.Thereafter = 3
+	}))
+
+	for i := 1; i <= 8; i++ {
+		logger.Info("info", "i", i)
+		logger.Debug("debug", "i", i)
+	}
+	logger.Error("error")
+	clock.Advance(time.Second)
+	logger.Info("info", "i", 9)
+
+	want := []string{
+		"level=INFO msg=info i=1",
+		"level=DEBUG msg=debug i=1",
+		"level=INFO msg=info i=2",
+		"level=DEBUG msg=debug i=2",
+		"level=INFO msg=info i=5",
+		"level=DEBUG msg=debug i=5",
+		"level=INFO msg=info i=8",
+		"level=DEBUG msg=debug i=8",
+		"level=ERROR msg=error",
+		"level=INFO msg=info i=9",
+	}
+	if diff, ok := helper.Equal(strings.Split(strings.TrimSpace(buf.String()), "\n"), want); !ok {
+		t.Error(helper.Message(t, "unexpected logs", diff))
+	}
+}
+
+func TestSamplingHandlerDedup(t *testing.T) {
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	handler := newTestSamplingHandler(&buf, clock, func(opt *SamplingOption) {
+		opt.Window = time.Minute
+	})
+	logger := slog.New(handler)
+	component := logger.With("component", "db")
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("retry", "attempt", 1)
+	}
+	component.Warn("retry", "attempt", 1)
+	component.Warn("retry", "attempt", 1)
+	clock.Advance(time.Minute)
+	component.Warn("retry", "attempt", 1)
+	component.Warn("retry", "attempt", 1)
+	if err := handler.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"level=WARN msg=retry attempt=1",
+		"level=WARN msg=retry component=db attempt=1",
+		"level=WARN msg=retry attempt=1 repeated=2",
+		"level=WARN msg=retry component=db attempt=1 repeated=1",
+		"level=WARN msg=retry component=db attempt=1",
+		"level=WARN msg=retry component=db attempt=1 repeated=1",
+	}
+	if diff, ok := helper.Equal(strings.Split(strings.TrimSpace(buf.String()), "\n"), want); !ok {
+		t.Error(helper.Message(t, "unexpected logs", diff))
+	}
+}
+
+func TestSamplingHandlerDedupInterleaved(t *testing.T) {
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	handler := newTestSamplingHandler(&buf, clock, func(opt *SamplingOption) {
+		opt.Window = time.Minute
+	})
+	logger := slog.New(handler)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("connected")
+		logger.Info("query", "table", "users")
+		clock.Advance(time.Second)
+	}
+	if diff, ok := helper.Equal(strings.Split(strings.TrimSpace(buf.String()), "\n"), []string{
+		"level=INFO msg=connected",
+		"level=INFO msg=query table=users",
+	}); !ok {
+		t.Error(helper.Message(t, "unexpected logs within the window", diff))
+	}
+
+	buf.Reset()
+	clock.Advance(time.Minute)
+	if diff, ok := helper.Equal(strings.Split(strings.TrimSpace(buf.String()), "\n"), []string{
+		"level=INFO msg=connected repeated=2",
+		"level=INFO msg=query table=users repeated=2",
+	}); !ok {
+		t.Error(helper.Message(t, "unexpected summaries at the end of the window", diff))
+	}
+
+	buf.Reset()
+	clock.Advance(time.Minute)
+	if err := handler.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(buf.String(), ""); !ok {
+		t.Error(helper.Message(t, "summaries repeated", diff))
+	}
+	if diff, ok := helper.Equal(len(handler.state.dedups), 0); !ok {
+		t.Error(helper.Message(t, "windows kept after their end", diff))
+	}
+}
+
+// reentrantHandler logs through the logger when it handles a summary.
+type reentrantHandler struct {
+	slog.Handler
+	logger **slog.Logger
+}
+
+func (h reentrantHandler) Handle(ctx context.Context, record slog.Record) error {
+	summary := false
+	record.Attrs(func(attr slog.Attr) bool {
+		summary = summary || attr.Key == RepeatedKey
+		return true
+	})
+	if summary {
+		(*h.logger).Info("summarized")
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func TestSamplingHandlerUnlocked(t *testing.T) {
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var logger *slog.Logger
+	handler := NewSamplingHandler(reentrantHandler{
+		Handler: slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: dropTime}),
+		logger:  &logger,
+	}, func(opt *SamplingOption) {
+		opt.Window = time.Minute
+	})
+	handler.state.now, handler.state.afterFunc = clock.Now, clock.AfterFunc
+	logger = slog.New(handler)
+
+	logger.Info("retry")
+	logger.Info("retry")
+	clock.Advance(time.Minute)
+	logger.Info("retry")
+	logger.Info("retry")
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"level=INFO msg=retry",
+		"level=INFO msg=summarized",
+		"level=INFO msg=retry repeated=1",
+		"level=INFO msg=retry",
+		"level=INFO msg=summarized",
+		"level=INFO msg=retry repeated=1",
+	}
+	if diff, ok := helper.Equal(strings.Split(strings.TrimSpace(buf.String()), "\n"), want); !ok {
+		t.Error(helper.Message(t, "unexpected logs", diff))
+	}
+}
+
+func TestSamplingConfiguration(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gaplog.toml")
+	data := "type = \"console\"\n\n[sampling]\ninterval = \"1s\"\nfirst = 1\nthereafter = 0\ndedup = \"1m\"\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ConfigurationFromViper(ViperConfiguration{ConfigFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SamplingConfiguration{Interval: time.Second, First: 1, Level: "info", Window: time.Minute}
+	if diff, ok := helper.Equal(config.Sampling, want); !ok {
+		t.Error(helper.Message(t, "unexpected configuration", diff))
+	}
+
+	logger, closer, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if _, ok := logger.Handler().(*ContextHandler).handler.(*SamplingHandler); !ok {
+		t.Error(helper.Message(t, "sampling handler not used"))
+	}
+}
@@ -0,0 +1,221 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogTimeFormat is the layout of the timestamp of RFC 5424.
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+	// syslogNilValue is the NILVALUE of RFC 5424.
+	syslogNilValue = "-"
+)
+
+// Severities of syslog.
+const (
+	severityCritical = 2
+	severityError    = 3
+	severityWarning  = 4
+	severityInfo     = 6
+	severityDebug    = 7
+)
+
+var (
+	syslogFacilities = map[string]int{
+		"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+		"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+		"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+		"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+	}
+
+	// syslogEscaper escapes the characters of the values of the structured data.
+	syslogEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+)
+
+// SyslogConfiguration is the configuration of a syslog sink.
+type SyslogConfiguration struct {
+	Network  string `toml:"network" mapstructure:"network" default:"unixgram" validate:"omitempty,oneof=unixgram unix udp tcp" comment:"Network of the syslog server: unixgram or unix for a local socket, udp or tcp."`
+	Address  string `toml:"address" mapstructure:"address" default:"/dev/log" comment:"Address of the syslog server, a socket path or a host:port."`
+	Framing  string `toml:"framing" mapstructure:"framing" default:"octet" validate:"omitempty,oneof=octet newline" comment:"Framing of the messages over unix and tcp: octet counting or newline, which escapes the newlines of the messages."`
+	Facility string `toml:"facility" mapstructure:"facility" default:"user" validate:"omitempty,oneof=kern user mail daemon auth syslog lpr news uucp cron authpriv ftp local0 local1 local2 local3 local4 local5 local6 local7" comment:"Facility of the logs."`
+	AppName  string `toml:"appname" mapstructure:"appname" comment:"Name of the application, the name of the executable by default."`
+	SDID     string `toml:"sdid" mapstructure:"sdid" default:"attrs@32473" comment:"ID of the structured data holding the attributes of the logs."`
+}
+
+// SyslogHandler is a slog.Handler sending the records to a syslog server as RFC 5424 messages,
+// with the attributes as structured data. The levels are mapped to the severities: fatal to
+// critical, error, warning, info to informational, and debug and trace to debug.
+type SyslogHandler struct {
+	fieldHandler
+	conn *syslogConn
+}
+
+type syslogConn struct {
+	network string
+	address string
+	framing string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewSyslogHandler creates a SyslogHandler connected to the server of the configuration.
+func NewSyslogHandler(config SyslogConfiguration, opts *slog.HandlerOptions) (*SyslogHandler, error) {
+	facility, ok := syslogFacilities[config.Facility]
+	if !ok {
+		return nil, fmt.Errorf("syslog::unknown facility %q", config.Facility)
+	}
+	appName := config.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = syslogNilValue
+	}
+
+	conn := &syslogConn{network: config.Network, address: config.Address, framing: config.Framing}
+	if err := conn.dial(); err != nil {
+		return nil, err
+	}
+	h := &SyslogHandler{conn: conn}
+	if opts != nil {
+		h.opts = *opts
+	}
+	header := fmt.Sprintf(" %s %s %d %s ",
+		syslogName(hostname, 255), syslogName(appName, 48), os.Getpid(), syslogNilValue)
+	sdid := syslogName(config.SDID, 32)
+	h.write = func(record slog.Record, fields []field) error {
+		var b strings.Builder
+		b.WriteString("<" + strconv.Itoa(facility*8+syslogSeverity(record.Level)) + ">1 ")
+		if record.Time.IsZero() {
+			b.WriteString(syslogNilValue)
+		} else {
+			b.WriteString(record.Time.Format(syslogTimeFormat))
+		}
+		b.WriteString(header)
+
+		if source, text, ok := h.source(record); ok {
+			if source != nil {
+				text = source.File + ":" + strconv.Itoa(source.Line)
+			}
+			fields = append(fields, field{key: slog.SourceKey, value: slog.StringValue(text)})
+		}
+		if len(fields) == 0 {
+			b.WriteString(syslogNilValue)
+		} else {
+			b.WriteString("[" + sdid)
+			for _, f := range fields {
+				b.WriteString(" " + syslogName(f.key, 32) + `="` + syslogEscape(f.value.String()) + `"`)
+			}
+			b.WriteString("]")
+		}
+		if message, ok := h.message(record); ok && message != "" {
+			b.WriteString(" " + message)
+		}
+		return conn.write([]byte(b.String()))
+	}
+	return h, nil
+}
+
+// Close closes the connection to the syslog server.
+func (h *SyslogHandler) Close() error {
+	h.conn.mu.Lock()
+	defer h.conn.mu.Unlock()
+
+	h.conn.closed = true
+	if h.conn.conn == nil {
+		return nil
+	}
+	err := h.conn.conn.Close()
+	h.conn.conn = nil
+	return err
+}
+
+func (c *syslogConn) dial() error {
+	conn, err := net.DialTimeout(c.network, c.address, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("syslog::%w", err)
+	}
+	c.conn = conn
+	return nil
+}
+
+// write sends the message, reconnecting once if the connection is broken. With the newline
+// framing, the newlines of the message are escaped as \n so that it stays in its frame.
+func (c *syslogConn) write(msg []byte) error {
+	if c.network == "unix" || c.network == "tcp" {
+		if c.framing == "newline" {
+			msg = append(bytes.ReplaceAll(msg, []byte("\n"), []byte(`\n`)), '\n')
+		} else {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("syslog::%w", net.ErrClosed)
+	}
+	if c.conn != nil {
+		if _, err := c.conn.Write(msg); err == nil {
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	if err := c.dial(); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+// syslogSeverity maps the level to a severity of syslog.
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelFatal:
+		return severityCritical
+	case level >= slog.LevelError:
+		return severityError
+	case level >= slog.LevelWarn:
+		return severityWarning
+	case level >= slog.LevelInfo:
+		return severityInfo
+	default:
+		return severityDebug
+	}
+}
+
+// syslogName replaces the characters not allowed in the header fields and the names of the
+// structured data, and truncates them to the maximum length.
+func syslogName(name string, maxLength int) string {
+	if name == "" {
+		return syslogNilValue
+	}
+	b := []byte(name)
+	for i, c := range b {
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > maxLength {
+		b = b[:maxLength]
+	}
+	return string(b)
+}
+
+// syslogEscape escapes the characters of a value of the structured data.
+func syslogEscape(value string) string {
+	return syslogEscaper.Replace(value)
+}
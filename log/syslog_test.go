@@ -0,0 +1,240 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+// syslogPattern matches the messages of the syslog tests, whose timestamp and hostname vary.
+var syslogPattern = regexp.MustCompile(`^<(\d+)>1 \S+ \S+ gap ` + strconv.Itoa(os.Getpid()) + ` - (.*)$`)
+
+func newTestSyslog(t *testing.T, network, address, framing string) *slog.Logger {
+	t.Helper()
+
+	config := defaultConfig.Syslog
+	config.Network, config.Address, config.Framing = network, address, framing
+	config.AppName, config.Facility = "gap", "local0"
+	handler, err := NewSyslogHandler(config, &slog.HandlerOptions{Level: LevelTrace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { handler.Close() })
+	return slog.New(handler)
+}
+
+// logSyslog logs the messages of the syslog tests and returns their expected content.
+func logSyslog(logger *slog.Logger) []string {
+	logger.Info("hello")
+	logger.With("user", "gopher").WithGroup("request").Warn("quoted", "path", `/a"b]`, "id", 1)
+	logger.Log(context.Background(), LevelFatal, "fatal", slog.Group("db", "host", "localhost"))
+	logger.Log(context.Background(), LevelTrace, "")
+	return []string{
+		"134 - hello",
+		`132 [attrs@32473 user="gopher" request.path="/a\"b\]" request.id="1"] quoted`,
+		`130 [attrs@32473 db.host="localhost"] fatal`,
+		"135 -",
+	}
+}
+
+func matchSyslog(t *testing.T, messages []string, want []string) {
+	t.Helper()
+
+	got := make([]string, len(messages))
+	for i, message := range messages {
+		match := syslogPattern.FindStringSubmatch(message)
+		if match == nil {
+			t.Fatal(helper.Message(t, "unexpected message", message))
+		}
+		got[i] = match[1] + " " + match[2]
+	}
+	if diff, ok := helper.Equal(got, want); !ok {
+		t.Error(helper.Message(t, "unexpected messages", diff))
+	}
+}
+
+func TestSyslogHandlerUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	want := logSyslog(newTestSyslog(t, "udp", conn.LocalAddr().String(), "octet"))
+	var messages []string
+	buf := make([]byte, 64<<10)
+	for range want {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, string(buf[:n]))
+	}
+	matchSyslog(t, messages, want)
+}
+
+func TestSyslogHandlerTCP(t *testing.T) {
+	for _, framing := range []string{"octet", "newline"} {
+		t.Run(framing, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+
+			want := logSyslog(newTestSyslog(t, "tcp", listener.Addr().String(), framing))
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			var messages []string
+			for range want {
+				var message string
+				if framing == "newline" {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						t.Fatal(err)
+					}
+					message = strings.TrimSuffix(line, "\n")
+				} else {
+					var length int
+					if _, err := fmt.Fscanf(reader, "%d ", &length); err != nil {
+						t.Fatal(err)
+					}
+					data := make([]byte, length)
+					if _, err := io.ReadFull(reader, data); err != nil {
+						t.Fatal(err)
+					}
+					message = string(data)
+				}
+				messages = append(messages, message)
+			}
+			matchSyslog(t, messages, want)
+		})
+	}
+}
+
+// replaceBuiltins replaces the message in uppercase, or drops it if it is "dropped", and the
+// source by the base name of its file.
+func replaceBuiltins(groups []string, attr slog.Attr) slog.Attr {
+	switch attr.Key {
+	case slog.MessageKey:
+		if attr.Value.String() == "dropped" {
+			return slog.Attr{}
+		}
+		return slog.String(attr.Key, strings.ToUpper(attr.Value.String()))
+	case slog.SourceKey:
+		return slog.String(attr.Key, filepath.Base(attr.Value.Any().(*slog.Source).File))
+	}
+	return attr
+}
+
+func TestSyslogHandlerReplaceAttr(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	config := defaultConfig.Syslog
+	config.Network, config.Address = "udp", conn.LocalAddr().String()
+	config.AppName, config.Facility = "gap", "local0"
+	handler, err := NewSyslogHandler(config, &slog.HandlerOptions{AddSource: true, ReplaceAttr: replaceBuiltins})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+	logger := slog.New(handler)
+	logger.Info("hello")
+	logger.Info("dropped")
+
+	var messages []string
+	buf := make([]byte, 64<<10)
+	for i := 0; i < 2; i++ {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, string(buf[:n]))
+	}
+	matchSyslog(t, messages, []string{
+		`134 [attrs@32473 source="syslog_test.go"] HELLO`,
+		`134 [attrs@32473 source="syslog_test.go"]`,
+	})
+}
+
+func TestSyslogHandlerNewline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	logger := newTestSyslog(t, "tcp", listener.Addr().String(), "newline")
+	logger.Info("two\nlines", "value", "a\nb")
+	logger.Info("next")
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	var messages []string
+	for i := 0; i < 2; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, strings.TrimSuffix(line, "\n"))
+	}
+	matchSyslog(t, messages, []string{
+		`134 [attrs@32473 value="a\nb"] two\nlines`,
+		"134 - next",
+	})
+}
+
+func TestSyslogHandlerErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := defaultConfig
+	config.Type = "syslog"
+	config.Syslog.Network, config.Syslog.Address = "tcp", address
+	if _, _, err := New(config); err == nil {
+		t.Error(helper.Message(t, "logger created without a syslog server"))
+	}
+}
+
+func TestSyslogName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: "-"},
+		{name: "user.id", want: "user.id"},
+		{name: `a b=c]d"é`, want: "a_b_c_d___"},
+		{name: strings.Repeat("k", 40), want: strings.Repeat("k", 32)},
+	}
+	for _, tt := range tests {
+		if diff, ok := helper.Equal(syslogName(tt.name, 32), tt.want); !ok {
+			t.Error(helper.Message(t, "unexpected name", diff))
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package log
 
 import (
+	"log/slog"
+	"reflect"
+
+	"github.com/creasty/defaults"
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
@@ -18,14 +24,40 @@ type ViperConfiguration struct {
 // LoadFromViper loads the Configuration using viper. A default configuration is returned if it
 // fails to load from viper.
 func ConfigurationFromViper(v ViperConfiguration) (Configuration, error) {
-	logViper := viper.New()
+	logViper := newViper(v)
+	if err := logViper.ReadInConfig(); err != nil {
+		return defaultConfig, err
+	}
+	return configurationFromViper(logViper)
+}
+
+// WatchLevels reads the configuration using viper and applies the levels of its sinks to the
+// levels whenever the configuration file changes. A configuration failing to load is logged and
+// ignored. The file is watched for the lifetime of the program.
+func WatchLevels(v ViperConfiguration, levels *Levels) error {
+	logViper := newViper(v)
+	if err := logViper.ReadInConfig(); err != nil {
+		return err
+	}
+	logViper.OnConfigChange(func(event fsnotify.Event) {
+		config, err := configurationFromViper(logViper)
+		if err != nil {
+			slog.Warn("failed to reload the log levels", "file", event.Name, "error", err)
+			return
+		}
+		levels.apply(config)
+	})
+	logViper.WatchConfig()
+	return nil
+}
+
+func newViper(v ViperConfiguration) *viper.Viper {
+	// The keys of the levels of the components may contain dots.
+	logViper := viper.NewWithOptions(viper.KeyDelimiter("::"))
 	if v.EnvPrefix != "" {
 		logViper.AutomaticEnv()
 		logViper.SetEnvPrefix(v.EnvPrefix)
 	}
-	if v.ConfigFile != "" {
-		logViper.SetConfigFile(v.ConfigFile)
-	}
 	if v.ConfigName != "" {
 		logViper.SetConfigName(v.ConfigName)
 	}
@@ -34,20 +66,44 @@ func ConfigurationFromViper(v ViperConfiguration) (Configuration, error) {
 			logViper.AddConfigPath(path)
 		}
 	}
+	if v.ConfigFile != "" {
+		// The config file must be set after the config name, which resets it.
+		logViper.SetConfigFile(v.ConfigFile)
+	}
 	if v.FileSystem != nil {
 		logViper.SetFs(v.FileSystem)
 	}
+	return logViper
+}
 
-	if err := logViper.ReadInConfig(); err != nil {
-		return defaultConfig, err
-	}
-
+func configurationFromViper(logViper *viper.Viper) (Configuration, error) {
 	config := defaultConfig
-	if err := logViper.Unmarshal(&config); err != nil {
+	hook := mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		defaultsHook,
+	)
+	if err := logViper.Unmarshal(&config, viper.DecodeHook(hook)); err != nil {
 		return defaultConfig, err
 	}
 	if err := validator.New().Struct(config); err != nil {
 		return defaultConfig, err
 	}
+	if err := validateComponents(config.Components); err != nil {
+		return defaultConfig, err
+	}
 	return config, nil
 }
+
+// defaultsHook sets the defaults of the structs of the package before decoding them, as the
+// elements of the slices, e.g. the sinks, are created by the decoder instead of coming from the
+// default configuration.
+func defaultsHook(from reflect.Value, to reflect.Value) (any, error) {
+	if to.Kind() != reflect.Struct || to.Type().PkgPath() != reflect.TypeOf(Configuration{}).PkgPath() || !to.CanAddr() {
+		return from.Interface(), nil
+	}
+	if err := defaults.Set(to.Addr().Interface()); err != nil {
+		return nil, err
+	}
+	return from.Interface(), nil
+}
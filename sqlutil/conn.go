@@ -58,6 +58,23 @@ func WithTx(ctx context.Context, db *sql.DB) (context.Context, func(error) error
 	return ctx, cancel, nil
 }
 
+// TxID returns the ID of the transaction in context.
+func TxID(ctx context.Context) (uuid.UUID, bool) {
+	if key, ok := ctx.Value(txCtx{}).(*txKey); ok {
+		return key.uuid, true
+	}
+	return uuid.Nil, false
+}
+
+// LogAttrs returns the ID of the transaction in context as a log attribute, to be added to the
+// logs of the context, e.g. by setting it in the ContextAttrs of the log configuration.
+func LogAttrs(ctx context.Context) []slog.Attr {
+	if id, ok := TxID(ctx); ok {
+		return []slog.Attr{slog.String("tx", id.String())}
+	}
+	return nil
+}
+
 // NewConn gets the Conn with the transaction in context or from a SQL connection.
 func NewConn(ctx context.Context, db SQL, opts ...func(*Conn)) (conn *Conn) {
 	conn = &Conn{SQL: db, Charset: "utf8mb4"}
@@ -0,0 +1,115 @@
+package sqlutil
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+func TestWithTxLogAttrs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	ctx, cancel, err := WithTx(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := cancel(nil); err != nil {
+			t.Error(helper.Message(t, "unexpected commit error", err.Error()))
+		}
+	}()
+	id, ok := TxID(ctx)
+	if !ok {
+		t.Fatal(helper.Message(t, "no transaction in context"))
+	}
+
+	if diff, ok := helper.Equal(LogAttrs(ctx), []slog.Attr{slog.String("tx", id.String())}); !ok {
+		t.Error(helper.Message(t, "unexpected attributes of the transaction", diff))
+	}
+	if diff, ok := helper.Equal(LogAttrs(context.Background()), []slog.Attr(nil)); !ok {
+		t.Error(helper.Message(t, "unexpected attributes without transaction", diff))
+	}
+}
+
+type testUser struct {
+	ID   int
+	Name string
+}
+
+func (u *testUser) Scan(row SQLRow) error {
+	return row.Scan(&u.ID, &u.Name)
+}
+
+func TestSQLQueryEach(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    func(*sqlmock.Rows) *sqlmock.Rows
+		fn      func(SQLScan) error
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "all rows",
+			want: []string{"gopher", "gordon"},
+		},
+		{
+			name:    "callback error",
+			fn:      func(SQLScan) error { return errors.New("stop") },
+			want:    []string{"gopher"},
+			wantErr: "stop",
+		},
+		{
+			name:    "rows error",
+			rows:    func(rows *sqlmock.Rows) *sqlmock.Rows { return rows.RowError(1, errors.New("row")) },
+			want:    []string{"gopher"},
+			wantErr: "sql::row",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			rows := sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "gopher").AddRow(2, "gordon")
+			if tt.rows != nil {
+				rows = tt.rows(rows)
+			}
+			mock.ExpectQuery("SELECT id, name FROM users").WithArgs(true).WillReturnRows(rows)
+
+			var names []string
+			session := SQLQuerySession{Query: "SELECT id, name FROM users WHERE active = ?", Args: []any{true}, Scanner: &testUser{}}
+			err = SQLQueryEach(context.Background(), NewConn(context.Background(), db), session, func(result SQLScan) error {
+				names = append(names, result.(*testUser).Name)
+				if tt.fn != nil {
+					return tt.fn(result)
+				}
+				return nil
+			})
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if diff, ok := helper.Equal(gotErr, tt.wantErr); !ok {
+				t.Error(helper.Message(t, "unexpected error", diff))
+			}
+			if diff, ok := helper.Equal(names, tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected rows", diff))
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(helper.Message(t, "unmet expectations", err.Error()))
+			}
+		})
+	}
+}
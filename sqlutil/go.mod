@@ -3,12 +3,17 @@ module github.com/shangkuei/gap/sqlutil
 go 1.22
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/google/uuid v1.4.0
+	github.com/shangkuei/gap/testhelper v0.0.1
 	go.uber.org/multierr v1.11.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
 )
+
+replace github.com/shangkuei/gap/testhelper => ../testhelper
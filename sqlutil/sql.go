@@ -68,6 +68,39 @@ func SQLQuery(ctx context.Context, conn *Conn, session SQLQuerySession) (results
 	return results, errs
 }
 
+// SQLQueryEach queries a SQL query and calls fn with each scanned row as it is read, e.g. to
+// stream the results into a json.Writer instead of collecting them. The errors of the scans are
+// collected like SQLQuery, while an error returned by fn stops the query.
+func SQLQueryEach(ctx context.Context, conn *Conn, session SQLQuerySession, fn func(SQLScan) error) (errs error) {
+	if session.Query == "" {
+		return nil
+	}
+
+	rows, err := conn.QueryContext(ctx, session.Query, session.Args...)
+	if err != nil {
+		return SQLError{query: session.Query, args: session.Args, err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result SQLScan
+		if session.Scanner != nil {
+			result = reflect.New(reflect.TypeOf(session.Scanner).Elem()).Interface().(SQLScan)
+			if err := result.Scan(rows); err != nil {
+				errs = multierr.Append(errs, err)
+				continue
+			}
+		}
+		if err := fn(result); err != nil {
+			return multierr.Append(errs, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		errs = multierr.Append(errs, SQLError{query: session.Query, args: session.Args, err: err})
+	}
+	return errs
+}
+
 // SQLExecSession is a SQL execution.
 type SQLExecSession struct {
 	Query    string
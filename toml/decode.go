@@ -1,22 +1,51 @@
 package toml
 
 import (
+	"bytes"
+	"encoding"
 	"io"
+	"reflect"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/pelletier/go-toml/v2"
+	"github.com/shangkuei/gap/codec"
 )
 
-// Decode decodes yaml encoded data from the reader and stores the result in the value pointed to by result.
+// DecodeOption is a type for functional options for the DecodeWith function.
+type DecodeOption = codec.DecodeOption
+
+// customTypes are the types which go-toml decodes in its own way.
+var customTypes = []reflect.Type{
+	reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem(),
+}
+
+// Decode decodes toml encoded data from the reader and stores the result in the value pointed to by result.
 func Decode[S any](reader io.Reader, result *S, hooks ...mapstructure.DecodeHookFunc) error {
-	var data any
-	if err := toml.NewDecoder(reader).Decode(&data); err != nil {
+	return DecodeWith(reader, result, codec.WithHooks(hooks...))
+}
+
+// DecodeWith decodes toml encoded data from the reader with the options and stores the result in
+// the value pointed to by result. The data is decoded directly into the result if possible, see
+// codec.DecodeOption.Direct.
+func DecodeWith[S any](reader io.Reader, result *S, opts ...func(*DecodeOption)) error {
+	opt := codec.NewDecodeOption(opts...)
+	raw, err := opt.ReadAll(reader)
+	if err != nil {
 		return err
 	}
+	opt.Locate(func(lines codec.KeyLines) { locate(raw, lines) })
+	if opt.Direct(result, []string{"toml"}, customTypes...) {
+		// On failure, the document tree is decoded to report all the errors at once.
+		direct := new(S)
+		if err := toml.NewDecoder(bytes.NewReader(raw)).Decode(direct); err == nil {
+			*result = *direct
+			return nil
+		}
+	}
 
-	decoder, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		DecodeHook: mapstructure.ComposeDecodeHookFunc(hooks...),
-		Result:     result,
-	})
-	return decoder.Decode(data)
+	var data any
+	if err := toml.NewDecoder(bytes.NewReader(raw)).Decode(&data); err != nil {
+		return err
+	}
+	return codec.Decode(data, result, opt)
 }
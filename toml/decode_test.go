@@ -0,0 +1,139 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shangkuei/gap/codec"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type benchmarkRecord struct {
+	ID    int               `toml:"id" mapstructure:"id"`
+	Name  string            `toml:"name" mapstructure:"name"`
+	Score float64           `toml:"score" mapstructure:"score"`
+	Tags  []string          `toml:"tags" mapstructure:"tags"`
+	Attrs map[string]string `toml:"attrs" mapstructure:"attrs"`
+}
+
+type benchmarkDocument struct {
+	Records []benchmarkRecord `toml:"records" mapstructure:"records"`
+}
+
+func BenchmarkDecode(b *testing.B) {
+	var document benchmarkDocument
+	for i := 0; i < 100; i++ {
+		document.Records = append(document.Records, benchmarkRecord{
+			ID:    i,
+			Name:  fmt.Sprintf("record-%d", i),
+			Score: float64(i) / 3,
+			Tags:  []string{"a", "b", "c"},
+			Attrs: map[string]string{"key": "value"},
+		})
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, document); err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarks := []struct {
+		name string
+		tree bool
+	}{
+		{name: "tree", tree: true},
+		{name: "direct"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var result benchmarkDocument
+				err := DecodeWith(bytes.NewReader(buf.Bytes()), &result, func(opt *DecodeOption) {
+					opt.Tree = bm.tree
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	raw := `level = "info"
+
+[[servers]]
+host = "localhost"
+ports = [80, {tls = 443}]
+
+[servers.limits]
+rate = 10
+
+[[servers]]
+hostname = "example.com"
+`
+	lines := make(codec.KeyLines)
+	locate([]byte(raw), lines)
+	want := codec.KeyLines{}
+	want.Set([]string{"level"}, 1)
+	want.Set([]string{"servers"}, 3)
+	want.Set([]string{"servers", "0", "host"}, 4)
+	want.Set([]string{"servers", "0", "ports"}, 5)
+	want.Set([]string{"servers", "0", "ports", "1", "tls"}, 5)
+	want.Set([]string{"servers", "0", "limits"}, 7)
+	want.Set([]string{"servers", "0", "limits", "rate"}, 8)
+	want.Set([]string{"servers", "1", "hostname"}, 11)
+	if diff, ok := helper.Equal(lines, want); !ok {
+		t.Error(helper.Message(t, "unexpected lines", diff))
+	}
+}
+
+type directConfig struct {
+	Level string `toml:"level" mapstructure:"level"`
+	Port  int    `toml:"port" mapstructure:"port"`
+	Data  []byte `toml:"data" mapstructure:"data"`
+	Ports [2]int `toml:"ports" mapstructure:"ports"`
+}
+
+// TestDecodeDirect checks that decoding directly has the same results as through the document tree.
+func TestDecodeDirect(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		result directConfig
+		want   directConfig
+	}{
+		{name: "valid", data: "level = 'info'\nport = 80\n", want: directConfig{Level: "info", Port: 80}},
+		{name: "key case", data: "Level = 'info'\nPORT = 80\n", want: directConfig{Level: "info", Port: 80}},
+		{name: "invalid", data: "port = 80\nlevel = 123\n", want: directConfig{Port: 80}},
+		{name: "set value", data: "port = 80\n", result: directConfig{Level: "info"}, want: directConfig{Level: "info", Port: 80}},
+		{name: "byte slice", data: "data = 'aGVsbG8='\n"},
+		{name: "long array", data: "ports = [80, 443, 8080]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]directConfig, 2)
+			errs := make([]string, 2)
+			for i, tree := range []bool{false, true} {
+				results[i] = tt.result
+				err := DecodeWith(strings.NewReader(tt.data), &results[i], func(opt *DecodeOption) {
+					opt.Tree = tree
+				})
+				if err != nil {
+					errs[i] = err.Error()
+				}
+			}
+			if diff, ok := helper.Equal(results[0], tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+			if diff, ok := helper.Equal(results[0], results[1]); !ok {
+				t.Error(helper.Message(t, "direct result differs", diff))
+			}
+			if diff, ok := helper.Equal(errs[0], errs[1]); !ok {
+				t.Error(helper.Message(t, "direct error differs", diff))
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package toml
+
+import (
+	"encoding"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shangkuei/gap/codec"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// documentFormats format the values of the types documented as strings, in the same form as their
+// default tags, e.g. 5s and 0640, which mapstructure decodes back with
+// StringToTimeDurationHookFunc and weakly typed input respectively.
+var documentFormats = map[reflect.Type]func(reflect.Value) string{
+	reflect.TypeOf(time.Duration(0)): func(value reflect.Value) string {
+		return time.Duration(value.Int()).String()
+	},
+	reflect.TypeOf(fs.FileMode(0)): func(value reflect.Value) string {
+		return fmt.Sprintf("%#o", value.Uint())
+	},
+}
+
+// documentTypes caches the types derived by documentType.
+var documentTypes sync.Map
+
+type documentKey struct {
+	typ        reflect.Type
+	documented bool
+}
+
+// documentType derives a type from typ, where the fields tagged with the squash option are
+// flattened into their parents and, if documented, the comment tags are replaced with the ones
+// returned by codec.FieldComment and the types of documentFormats with strings. Recursive types
+// are kept as they are.
+func documentType(typ reflect.Type, documented bool) reflect.Type {
+	return deriveType(typ, documented, map[reflect.Type]bool{})
+}
+
+func deriveType(typ reflect.Type, documented bool, visiting map[reflect.Type]bool) reflect.Type {
+	key := documentKey{typ: typ, documented: documented}
+	if cached, ok := documentTypes.Load(key); ok {
+		return cached.(reflect.Type)
+	}
+	if visiting[typ] {
+		return typ
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	derived := typ
+	if _, ok := documentFormats[typ]; ok && documented {
+		derived = reflect.TypeOf("")
+	} else if !typ.Implements(textMarshalerType) {
+		switch typ.Kind() {
+		case reflect.Pointer:
+			derived = reflect.PointerTo(deriveType(typ.Elem(), documented, visiting))
+		case reflect.Slice:
+			derived = reflect.SliceOf(deriveType(typ.Elem(), documented, visiting))
+		case reflect.Array:
+			derived = reflect.ArrayOf(typ.Len(), deriveType(typ.Elem(), documented, visiting))
+		case reflect.Map:
+			derived = reflect.MapOf(typ.Key(), deriveType(typ.Elem(), documented, visiting))
+		case reflect.Struct:
+			var fields []reflect.StructField
+			for _, field := range documentFields(typ, documented, visiting) {
+				field.Name = fmt.Sprintf("F%d", len(fields))
+				fields = append(fields, field)
+			}
+			derived = reflect.StructOf(fields)
+		}
+	}
+	documentTypes.Store(key, derived)
+	return derived
+}
+
+// documentFields returns the fields of the struct type to be encoded, with the keys set in their
+// toml tags since their names are changed.
+func documentFields(typ reflect.Type, documented bool, visiting map[reflect.Type]bool) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("toml")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if opts["squash"] || (field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct) {
+			fields = append(fields, documentFields(field.Type, documented, visiting)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		options := []string{name}
+		for _, opt := range []string{"multiline", "inline", "omitempty", "commented"} {
+			if opts[opt] {
+				options = append(options, opt)
+			}
+		}
+		comment := field.Tag.Get("comment")
+		if documented {
+			comment = codec.FieldComment(field)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: field.Name,
+			Type: deriveType(field.Type, documented, visiting),
+			Tag: reflect.StructTag(fmt.Sprintf("toml:%s comment:%s",
+				strconv.Quote(strings.Join(options, ",")), strconv.Quote(comment))),
+		})
+	}
+	return fields
+}
+
+// documentValue copies the value into a value of the derived type.
+func documentValue(value reflect.Value, derived reflect.Type) reflect.Value {
+	if derived == value.Type() {
+		return value
+	}
+	if format, ok := documentFormats[value.Type()]; ok && derived.Kind() == reflect.String {
+		return reflect.ValueOf(format(value))
+	}
+
+	result := reflect.New(derived).Elem()
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			result.Set(reflect.New(derived.Elem()))
+			result.Elem().Set(documentValue(value.Elem(), derived.Elem()))
+		}
+	case reflect.Slice:
+		if !value.IsNil() {
+			result.Set(reflect.MakeSlice(derived, value.Len(), value.Len()))
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(documentValue(value.Index(i), derived.Elem()))
+		}
+	case reflect.Map:
+		if !value.IsNil() {
+			result.Set(reflect.MakeMapWithSize(derived, value.Len()))
+			iter := value.MapRange()
+			for iter.Next() {
+				result.SetMapIndex(iter.Key(), documentValue(iter.Value(), derived.Elem()))
+			}
+		}
+	case reflect.Struct:
+		for i, fieldValue := range documentFieldValues(value, nil) {
+			result.Field(i).Set(documentValue(fieldValue, derived.Field(i).Type))
+		}
+	}
+	return result
+}
+
+// documentFieldValues returns the values of the fields returned by documentFields in the same
+// order.
+func documentFieldValues(value reflect.Value, values []reflect.Value) []reflect.Value {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("toml")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if opts["squash"] || (field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct) {
+			values = documentFieldValues(value.Field(i), values)
+			continue
+		}
+		values = append(values, value.Field(i))
+	}
+	return values
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	name, rest, _ := strings.Cut(tag, ",")
+	opts := map[string]bool{}
+	for _, opt := range strings.Split(rest, ",") {
+		if opt != "" {
+			opts[opt] = true
+		}
+	}
+	return name, opts
+}
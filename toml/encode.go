@@ -2,8 +2,11 @@ package toml
 
 import (
 	"io"
+	"reflect"
 
 	"github.com/pelletier/go-toml/v2"
+	"github.com/shangkuei/gap/codec"
+	"github.com/spf13/afero"
 )
 
 // EncodeOption is a type for functional options for the Encode function.
@@ -12,6 +15,18 @@ type EncodeOption struct {
 	IndentSymbol    string
 	IndentTables    bool
 	ArraysMultiline bool
+	// Documented emits a comment before each field for a sample configuration, made of its
+	// `comment` tag, the values allowed by the oneof rule of its `validate` tag and the value of
+	// its `default` tag. The fields tagged with the squash option are flattened into their
+	// parents as mapstructure does.
+	Documented bool
+	// OmitDefaults omits the fields equal to their defaults, see codec.OmitDefaults. The fields
+	// tagged with the squash option are flattened as well, since the omitted fields are derived.
+	OmitDefaults bool
+	// Unions encodes the values of the unions with their discriminators, see codec.EncodeUnions.
+	Unions []codec.Union
+	// File holds the options of EncodeFile.
+	File codec.FileOption
 }
 
 // Encode encodes data to the writer with toml.
@@ -26,5 +41,33 @@ func Encode[S any](writer io.Writer, data S, opts ...func(*EncodeOption)) error
 	encoder.SetIndentSymbol(opt.IndentSymbol)
 	encoder.SetIndentTables(opt.IndentTables)
 	encoder.SetArraysMultiline(opt.ArraysMultiline)
-	return encoder.Encode(data)
+
+	value := any(data)
+	if opt.OmitDefaults {
+		var err error
+		if value, err = codec.OmitDefaults(value); err != nil {
+			return err
+		}
+	}
+	value = codec.EncodeUnions(value, opt.Unions...)
+	if typ := reflect.TypeOf(value); typ != nil && (opt.Documented || opt.OmitDefaults) {
+		return encoder.Encode(documentValue(reflect.ValueOf(value), documentType(typ, opt.Documented)).Interface())
+	}
+	return encoder.Encode(value)
+}
+
+// EncodeFile atomically writes data encoded with toml to the file at path, see codec.WriteFile.
+func EncodeFile[S any](path string, data S, opts ...func(*EncodeOption)) error {
+	return EncodeFileFs(afero.NewOsFs(), path, data, opts...)
+}
+
+// EncodeFileFs is EncodeFile on the file system.
+func EncodeFileFs[S any](fsys afero.Fs, path string, data S, opts ...func(*EncodeOption)) error {
+	var opt EncodeOption
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	return codec.WriteFile(fsys, path, opt.File, func(writer io.Writer) error {
+		return Encode(writer, data, opts...)
+	})
 }
@@ -0,0 +1,120 @@
+package toml_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/shangkuei/gap/codec"
+	"github.com/shangkuei/gap/toml"
+)
+
+type sampleFile struct {
+	File       string      `toml:"file" comment:"Path of the log file."`
+	Permission fs.FileMode `toml:"permission" default:"0640" comment:"Permission of the log file."`
+}
+
+type sampleConfig struct {
+	Type  string     `toml:"type" default:"console" validate:"oneof=console file" comment:"Type of the logger."`
+	Level string     `toml:"level" default:"info" validate:"oneof=debug info warn error"`
+	File  sampleFile `toml:",omitempty,squash"`
+}
+
+func ExampleEncode_documented() {
+	config := sampleConfig{Type: "file", Level: "info", File: sampleFile{File: "app.log", Permission: 0640}}
+	err := toml.Encode(os.Stdout, config, func(opt *toml.EncodeOption) {
+		opt.Documented = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// # Type of the logger.
+	// # One of: console, file
+	// # Default: console
+	// type = 'file'
+	// # One of: debug, info, warn, error
+	// # Default: info
+	// level = 'info'
+	// # Path of the log file.
+	// file = 'app.log'
+	// # Permission of the log file.
+	// # Default: 0640
+	// permission = '0640'
+}
+
+type sampleOutput interface {
+	Describe() string
+}
+
+type sampleFileOutput struct {
+	Path string `toml:"path" mapstructure:"path"`
+}
+
+func (o *sampleFileOutput) Describe() string { return "file " + o.Path }
+
+type sampleConsoleOutput struct {
+	Stream string `toml:"stream" mapstructure:"stream"`
+}
+
+func (o *sampleConsoleOutput) Describe() string { return "console " + o.Stream }
+
+type sampleOutputs struct {
+	Outputs []sampleOutput `toml:"outputs" mapstructure:"outputs"`
+}
+
+func ExampleEncode_unions() {
+	outputs := codec.NewRegistry[sampleOutput]("type").
+		Register("file", &sampleFileOutput{}).
+		Register("console", &sampleConsoleOutput{})
+
+	config := sampleOutputs{Outputs: []sampleOutput{
+		&sampleConsoleOutput{Stream: "stderr"},
+		&sampleFileOutput{Path: "app.log"},
+	}}
+	var buf bytes.Buffer
+	err := toml.Encode(&buf, config, func(opt *toml.EncodeOption) {
+		opt.Unions = []codec.Union{outputs}
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(buf.String())
+
+	var decoded sampleOutputs
+	if err := toml.Decode(&buf, &decoded, outputs.DecodeHook()); err != nil {
+		panic(err)
+	}
+	for _, output := range decoded.Outputs {
+		fmt.Println(output.Describe())
+	}
+
+	// Output:
+	// [[outputs]]
+	// type = 'console'
+	// stream = 'stderr'
+	//
+	// [[outputs]]
+	// type = 'file'
+	// path = 'app.log'
+	// console stderr
+	// file app.log
+}
+
+func ExampleEncode_omitDefaults() {
+	config := sampleConfig{Type: "file", Level: "info", File: sampleFile{File: "app.log", Permission: 0640}}
+	err := toml.Encode(os.Stdout, config, func(opt *toml.EncodeOption) {
+		opt.OmitDefaults = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// # Type of the logger.
+	// type = 'file'
+	// # Path of the log file.
+	// file = 'app.log'
+}
@@ -5,13 +5,20 @@ go 1.22
 require (
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/pelletier/go-toml/v2 v2.2.2
+	github.com/shangkuei/gap/codec v0.0.1
 	github.com/shangkuei/gap/testhelper v0.0.1
+	github.com/spf13/afero v1.11.0
 )
 
 require (
+	github.com/creasty/defaults v1.7.0 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
 
-replace github.com/shangkuei/gap/testhelper => ../testhelper
+replace (
+	github.com/shangkuei/gap/codec => ../codec
+	github.com/shangkuei/gap/testhelper => ../testhelper
+)
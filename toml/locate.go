@@ -0,0 +1,66 @@
+package toml
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/shangkuei/gap/codec"
+)
+
+// locate records the lines of the keys of the toml document. The keys of the array tables are
+// indexed by the number of tables before them.
+func locate(raw []byte, lines codec.KeyLines) {
+	parser := unstable.Parser{}
+	parser.Reset(raw)
+	arrays := make(map[string]int)
+	var table []string
+	for parser.NextExpression() {
+		expression := parser.Expression()
+		switch expression.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = locateKey(&parser, expression.Key(), nil, arrays, lines)
+			if expression.Kind == unstable.ArrayTable {
+				name := strings.Join(table, "\x00")
+				table = append(table, strconv.Itoa(arrays[name]))
+				arrays[name]++
+			}
+		case unstable.KeyValue:
+			locateKeyValue(&parser, expression, table, arrays, lines)
+		}
+	}
+}
+
+// locateKey records the lines of the parts of a dotted key relative to the path, and returns the
+// path of the key, with the index of the last table of the array tables it goes through before
+// its last part.
+func locateKey(parser *unstable.Parser, key unstable.Iterator, path []string, arrays map[string]int, lines codec.KeyLines) []string {
+	path = path[:len(path):len(path)]
+	for key.Next() {
+		path = append(path, string(key.Node().Data))
+		lines.Set(path, parser.Shape(key.Node().Raw).Start.Line)
+		if count, ok := arrays[strings.Join(path, "\x00")]; ok && !key.IsLast() {
+			path = append(path, strconv.Itoa(count-1))
+		}
+	}
+	return path
+}
+
+func locateKeyValue(parser *unstable.Parser, node *unstable.Node, path []string, arrays map[string]int, lines codec.KeyLines) {
+	locateValue(parser, node.Value(), locateKey(parser, node.Key(), path, arrays, lines), arrays, lines)
+}
+
+func locateValue(parser *unstable.Parser, value *unstable.Node, path []string, arrays map[string]int, lines codec.KeyLines) {
+	switch value.Kind {
+	case unstable.InlineTable:
+		children := value.Children()
+		for children.Next() {
+			locateKeyValue(parser, children.Node(), path, arrays, lines)
+		}
+	case unstable.Array:
+		children := value.Children()
+		for i := 0; children.Next(); i++ {
+			locateValue(parser, children.Node(), append(path[:len(path):len(path)], strconv.Itoa(i)), arrays, lines)
+		}
+	}
+}
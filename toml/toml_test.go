@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/pelletier/go-toml/v2"
 	helper "github.com/shangkuei/gap/testhelper"
 )
 
@@ -42,3 +43,75 @@ func TestDecodeTOMLUnmarshalFunc(t *testing.T) {
 		})
 	}
 }
+
+type squashedFile struct {
+	File string `toml:"file"`
+}
+
+type squashedConfig struct {
+	Type string       `toml:"type"`
+	File squashedFile `toml:",squash"`
+}
+
+func TestEncodeSquash(t *testing.T) {
+	config := squashedConfig{Type: "file", File: squashedFile{File: "app.log"}}
+	plain, err := toml.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		documented bool
+		want       string
+	}{
+		{name: "plain", want: string(plain)},
+		{name: "documented", documented: true, want: "type = 'file'\nfile = 'app.log'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Encode(&buf, config, func(opt *EncodeOption) {
+				opt.Documented = tt.documented
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff, ok := helper.Equal(buf.String(), tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected document", diff))
+			}
+		})
+	}
+}
+
+type omittedFile struct {
+	File       string `toml:"file" mapstructure:"file"`
+	Permission uint32 `toml:"permission" mapstructure:"permission" default:"0640"`
+}
+
+type omittedConfig struct {
+	Type string      `toml:"type" mapstructure:"type" default:"console"`
+	File omittedFile `toml:",squash" mapstructure:",squash"`
+}
+
+func TestEncodeOmitDefaultsSquash(t *testing.T) {
+	config := omittedConfig{Type: "file", File: omittedFile{File: "app.log", Permission: 0640}}
+	var buf bytes.Buffer
+	err := Encode(&buf, config, func(opt *EncodeOption) {
+		opt.OmitDefaults = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(buf.String(), "type = 'file'\nfile = 'app.log'\n"); !ok {
+		t.Error(helper.Message(t, "unexpected document", diff))
+	}
+
+	got := omittedConfig{File: omittedFile{Permission: 0640}}
+	if err := Decode(&buf, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff, ok := helper.Equal(got, config); !ok {
+		t.Error(helper.Message(t, "unexpected decoded configuration", diff))
+	}
+}
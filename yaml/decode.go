@@ -1,22 +1,92 @@
 package yaml
 
 import (
+	"bytes"
 	"io"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 	"github.com/mitchellh/mapstructure"
+	"github.com/shangkuei/gap/codec"
 )
 
+// DecodeOption is a type for functional options for the DecodeWith function.
+type DecodeOption = codec.DecodeOption
+
 // Decode decodes yaml encoded data from the reader and stores the result in the value pointed to by result.
 func Decode[S any](reader io.Reader, result *S, hooks ...mapstructure.DecodeHookFunc) error {
+	return DecodeWith(reader, result, codec.WithHooks(hooks...))
+}
+
+// DecodeWith decodes yaml encoded data from the reader with the options and stores the result in
+// the value pointed to by result. Unlike the other codecs, the data is never decoded directly into
+// the result, see codec.DecodeOption.Direct, since go-yaml matches the keys case-sensitively and
+// converts the scalars between types, where mapstructure does not.
+func DecodeWith[S any](reader io.Reader, result *S, opts ...func(*DecodeOption)) error {
+	opt := codec.NewDecodeOption(opts...)
+	raw, err := opt.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if opt.Limits.MaxAliases > 0 {
+		if err := checkAliases(raw, opt.Limits.MaxAliases); err != nil {
+			return err
+		}
+	}
+	opt.Locate(func(lines codec.KeyLines) { locate(raw, lines) })
 	var data any
-	if err := yaml.NewDecoder(reader).Decode(&data); err != nil {
+	if err := yaml.NewDecoder(bytes.NewReader(raw)).Decode(&data); err != nil {
+		return err
+	}
+	return codec.Decode(data, result, opt)
+}
+
+// checkAliases counts the aliases of the first document as if they were expanded, before the
+// decoder expands them.
+func checkAliases(raw []byte, limit int) error {
+	file, err := parser.ParseBytes(raw, 0)
+	if err != nil {
 		return err
 	}
+	if len(file.Docs) == 0 {
+		return nil
+	}
+	if countAliases(file.Docs[0], map[string]int{}, limit) > limit {
+		return codec.LimitError{Limit: "aliases", Max: int64(limit)}
+	}
+	return nil
+}
+
+// countAliases returns the number of alias expansions in the node, which saturates at limit+1.
+func countAliases(node ast.Node, anchors map[string]int, limit int) (count int) {
+	add := func(n int) {
+		count = min(count+n, limit+1)
+	}
 
-	decoder, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		DecodeHook: mapstructure.ComposeDecodeHookFunc(hooks...),
-		Result:     result,
-	})
-	return decoder.Decode(data)
+	switch node := node.(type) {
+	case *ast.DocumentNode:
+		add(countAliases(node.Body, anchors, limit))
+	case *ast.AliasNode:
+		add(1 + anchors[node.Value.GetToken().Value])
+	case *ast.AnchorNode:
+		add(countAliases(node.Value, anchors, limit))
+		anchors[node.Name.GetToken().Value] = count
+	case *ast.TagNode:
+		add(countAliases(node.Value, anchors, limit))
+	case *ast.MappingNode:
+		for _, value := range node.Values {
+			add(countAliases(value, anchors, limit))
+		}
+	case *ast.MappingKeyNode:
+		add(countAliases(node.Value, anchors, limit))
+	case *ast.MappingValueNode:
+		add(countAliases(node.Key, anchors, limit))
+		add(countAliases(node.Value, anchors, limit))
+	case *ast.SequenceNode:
+		for _, value := range node.Values {
+			add(countAliases(value, anchors, limit))
+		}
+	}
+	return count
 }
@@ -0,0 +1,184 @@
+package yaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shangkuei/gap/codec"
+	helper "github.com/shangkuei/gap/testhelper"
+)
+
+type benchmarkRecord struct {
+	ID    int               `yaml:"id" mapstructure:"id"`
+	Name  string            `yaml:"name" mapstructure:"name"`
+	Score float64           `yaml:"score" mapstructure:"score"`
+	Tags  []string          `yaml:"tags" mapstructure:"tags"`
+	Attrs map[string]string `yaml:"attrs" mapstructure:"attrs"`
+}
+
+type benchmarkDocument struct {
+	Records []benchmarkRecord `yaml:"records" mapstructure:"records"`
+}
+
+// BenchmarkDecode decodes through the document tree only, since yaml is never decoded directly.
+func BenchmarkDecode(b *testing.B) {
+	var document benchmarkDocument
+	for i := 0; i < 100; i++ {
+		document.Records = append(document.Records, benchmarkRecord{
+			ID:    i,
+			Name:  fmt.Sprintf("record-%d", i),
+			Score: float64(i) / 3,
+			Tags:  []string{"a", "b", "c"},
+			Attrs: map[string]string{"key": "value"},
+		})
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, document); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var result benchmarkDocument
+		if err := DecodeWith(bytes.NewReader(buf.Bytes()), &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const billionLaughs = `
+a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g]
+i: &i [*h,*h,*h,*h,*h,*h,*h,*h,*h]
+`
+
+func TestDecodeWithLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		limits codec.Limits
+		want   *codec.LimitError
+	}{
+		{
+			name:   "aliases within limit",
+			data:   "a: &a [1, 2]\nb: *a\nc: *a\n",
+			limits: codec.Limits{MaxAliases: 2},
+		},
+		{
+			name:   "billion laughs",
+			data:   billionLaughs,
+			limits: codec.Limits{MaxAliases: 1000},
+			want:   &codec.LimitError{Limit: "aliases", Max: 1000},
+		},
+		{
+			name:   "bytes",
+			data:   billionLaughs,
+			limits: codec.Limits{MaxBytes: 64},
+			want:   &codec.LimitError{Limit: "bytes", Max: 64},
+		},
+		{
+			name:   "depth",
+			data:   "a:\n  b:\n    c: [1]\n",
+			limits: codec.Limits{MaxDepth: 3},
+			want:   &codec.LimitError{Limit: "depth", Max: 3, Path: "a.b.c"},
+		},
+		{
+			name:   "collection",
+			data:   "a: [1, 2, 3]\n",
+			limits: codec.Limits{MaxCollection: 2},
+			want:   &codec.LimitError{Limit: "collection", Max: 2, Path: "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result any
+			err := DecodeWith(strings.NewReader(tt.data), &result, codec.WithLimits(tt.limits))
+
+			var limitErr codec.LimitError
+			if got := errors.As(err, &limitErr); got != (tt.want != nil) {
+				t.Fatal(helper.Message(t, "unexpected error", fmt.Sprintf("Err: %v", err)))
+			}
+			if tt.want != nil {
+				if diff, ok := helper.Equal(limitErr, *tt.want); !ok {
+					t.Error(helper.Message(t, "unexpected limit error", diff))
+				}
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	raw := `level: info
+servers:
+  - host: localhost
+    ports: [80, {tls: 443}]
+  - &second
+    hostname: example.com
+`
+	lines := make(codec.KeyLines)
+	locate([]byte(raw), lines)
+	want := codec.KeyLines{}
+	want.Set([]string{"level"}, 1)
+	want.Set([]string{"servers"}, 2)
+	want.Set([]string{"servers", "0", "host"}, 3)
+	want.Set([]string{"servers", "0", "ports"}, 4)
+	want.Set([]string{"servers", "0", "ports", "1", "tls"}, 4)
+	want.Set([]string{"servers", "1", "hostname"}, 6)
+	if diff, ok := helper.Equal(lines, want); !ok {
+		t.Error(helper.Message(t, "unexpected lines", diff))
+	}
+}
+
+type directConfig struct {
+	Level string `yaml:"level" mapstructure:"level"`
+	Port  int    `yaml:"port" mapstructure:"port"`
+}
+
+// TestDecodeDirect checks that decoding directly has the same results as through the document tree.
+func TestDecodeDirect(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		result directConfig
+		want   directConfig
+	}{
+		{name: "valid", data: "level: info\nport: 80\n", want: directConfig{Level: "info", Port: 80}},
+		{name: "key case", data: "Level: info\nPORT: 80\n", want: directConfig{Level: "info", Port: 80}},
+		{name: "unknown key", data: "level: info\nhost: localhost\n", want: directConfig{Level: "info"}},
+		{name: "invalid", data: "level: 123\nport: 80\n", want: directConfig{Port: 80}},
+		{name: "set value", data: "port: 80\n", result: directConfig{Level: "info"}, want: directConfig{Level: "info", Port: 80}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]directConfig, 2)
+			errs := make([]string, 2)
+			for i, tree := range []bool{false, true} {
+				results[i] = tt.result
+				err := DecodeWith(strings.NewReader(tt.data), &results[i], func(opt *DecodeOption) {
+					opt.Tree = tree
+				})
+				if err != nil {
+					errs[i] = err.Error()
+				}
+			}
+			if diff, ok := helper.Equal(results[0], tt.want); !ok {
+				t.Error(helper.Message(t, "unexpected result", diff))
+			}
+			if diff, ok := helper.Equal(results[0], results[1]); !ok {
+				t.Error(helper.Message(t, "direct result differs", diff))
+			}
+			if diff, ok := helper.Equal(errs[0], errs[1]); !ok {
+				t.Error(helper.Message(t, "direct error differs", diff))
+			}
+		})
+	}
+}
@@ -2,13 +2,28 @@ package yaml
 
 import (
 	"io"
+	"reflect"
+	"strings"
 
 	"github.com/goccy/go-yaml"
+	"github.com/shangkuei/gap/codec"
+	"github.com/spf13/afero"
 )
 
 // EncodeOption is a type for functional options for the Encode function.
 type EncodeOption struct {
+	// Indent is the number of spaces of an indentation level. It defaults to the one of goccy/go-yaml.
 	Indent int
+	// Documented emits a comment before each field for a sample configuration, made of its
+	// `comment` tag, the values allowed by the oneof rule of its `validate` tag and the value of
+	// its `default` tag.
+	Documented bool
+	// OmitDefaults omits the fields equal to their defaults, see codec.OmitDefaults.
+	OmitDefaults bool
+	// Unions encodes the values of the unions with their discriminators, see codec.EncodeUnions.
+	Unions []codec.Union
+	// File holds the options of EncodeFile.
+	File codec.FileOption
 }
 
 // Encode encodes data to the writer with yaml.
@@ -18,6 +33,105 @@ func Encode[S any](writer io.Writer, data S, opts ...func(*EncodeOption)) error
 		fn(&opt)
 	}
 
-	encoder := yaml.NewEncoder(writer, yaml.Indent(opt.Indent))
-	return encoder.Encode(data)
+	value := any(data)
+	if opt.OmitDefaults {
+		var err error
+		if value, err = codec.OmitDefaults(value); err != nil {
+			return err
+		}
+	}
+	value = codec.EncodeUnions(value, opt.Unions...)
+	var options []yaml.EncodeOption
+	if opt.Indent > 0 {
+		options = append(options, yaml.Indent(opt.Indent))
+	}
+	if opt.Documented {
+		comments := yaml.CommentMap{}
+		documentComments(reflect.ValueOf(value), "$", comments)
+		options = append(options, yaml.WithComment(comments))
+	}
+	encoder := yaml.NewEncoder(writer, options...)
+	return encoder.Encode(value)
+}
+
+// documentComments collects the comments of the fields in the value by their paths. Like
+// go-toml, only the first element of a slice is documented.
+func documentComments(value reflect.Value, path string, comments yaml.CommentMap) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if value.Len() > 0 {
+			documentComments(value.Index(0), path+"[0]", comments)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if key, ok := iter.Key().Interface().(string); ok {
+				documentComments(iter.Value(), path+"."+key, comments)
+			}
+		}
+	case reflect.Struct:
+		typ := value.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			tag := field.Tag.Get("yaml")
+			if tag == "" {
+				tag = field.Tag.Get("json")
+			}
+			if !field.IsExported() || tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if strings.Contains(opts, "inline") {
+				documentComments(value.Field(i), path, comments)
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if strings.Contains(opts, "omitempty") && value.Field(i).IsZero() {
+				continue
+			}
+
+			fieldPath := path + "." + quotePathKey(name)
+			if comment := codec.FieldComment(field); comment != "" {
+				var texts []string
+				for _, line := range strings.Split(comment, "\n") {
+					texts = append(texts, " "+line)
+				}
+				comments[fieldPath] = []*yaml.Comment{yaml.HeadComment(texts...)}
+			}
+			documentComments(value.Field(i), fieldPath, comments)
+		}
+	}
+}
+
+// quotePathKey quotes a key of a YAML path if it has special characters.
+func quotePathKey(key string) string {
+	if strings.ContainsAny(key, ".[]*'\" ") {
+		return "'" + strings.ReplaceAll(key, "'", `\'`) + "'"
+	}
+	return key
+}
+
+// EncodeFile atomically writes data encoded with yaml to the file at path, see codec.WriteFile.
+func EncodeFile[S any](path string, data S, opts ...func(*EncodeOption)) error {
+	return EncodeFileFs(afero.NewOsFs(), path, data, opts...)
+}
+
+// EncodeFileFs is EncodeFile on the file system.
+func EncodeFileFs[S any](fsys afero.Fs, path string, data S, opts ...func(*EncodeOption)) error {
+	var opt EncodeOption
+	for _, fn := range opts {
+		fn(&opt)
+	}
+	return codec.WriteFile(fsys, path, opt.File, func(writer io.Writer) error {
+		return Encode(writer, data, opts...)
+	})
 }
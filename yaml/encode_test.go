@@ -0,0 +1,115 @@
+package yaml_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/shangkuei/gap/codec"
+	"github.com/shangkuei/gap/yaml"
+)
+
+type sampleServer struct {
+	Host string `yaml:"host" comment:"Host of the server."`
+	Port int    `yaml:"port" default:"80"`
+}
+
+type sampleConfig struct {
+	Level   string         `yaml:"level" default:"info" validate:"oneof=debug info warn error" comment:"Minimum level of the logs."`
+	Servers []sampleServer `yaml:"servers" comment:"Servers to connect to."`
+}
+
+func ExampleEncode_documented() {
+	config := sampleConfig{Level: "info", Servers: []sampleServer{{Host: "localhost", Port: 80}}}
+	err := yaml.Encode(os.Stdout, config, func(opt *yaml.EncodeOption) {
+		opt.Indent = 2
+		opt.Documented = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// # Minimum level of the logs.
+	// # One of: debug, info, warn, error
+	// # Default: info
+	// level: info
+	// # Servers to connect to.
+	// servers:
+	// - # Host of the server.
+	//   host: localhost
+	//   # Default: 80
+	//   port: 80
+}
+
+type sampleOutput interface {
+	Describe() string
+}
+
+type sampleFileOutput struct {
+	Path string `yaml:"path" mapstructure:"path"`
+}
+
+func (o *sampleFileOutput) Describe() string { return "file " + o.Path }
+
+type sampleConsoleOutput struct {
+	Stream string `yaml:"stream" mapstructure:"stream"`
+}
+
+func (o *sampleConsoleOutput) Describe() string { return "console " + o.Stream }
+
+type sampleOutputs struct {
+	Outputs []sampleOutput `yaml:"outputs" mapstructure:"outputs"`
+}
+
+func ExampleEncode_unions() {
+	outputs := codec.NewRegistry[sampleOutput]("type").
+		Register("file", &sampleFileOutput{}).
+		Register("console", &sampleConsoleOutput{})
+
+	config := sampleOutputs{Outputs: []sampleOutput{
+		&sampleConsoleOutput{Stream: "stderr"},
+		&sampleFileOutput{Path: "app.log"},
+	}}
+	var buf bytes.Buffer
+	err := yaml.Encode(&buf, config, func(opt *yaml.EncodeOption) {
+		opt.Unions = []codec.Union{outputs}
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(buf.String())
+
+	var decoded sampleOutputs
+	if err := yaml.Decode(&buf, &decoded, outputs.DecodeHook()); err != nil {
+		panic(err)
+	}
+	for _, output := range decoded.Outputs {
+		fmt.Println(output.Describe())
+	}
+
+	// Output:
+	// outputs:
+	// - type: console
+	//   stream: stderr
+	// - type: file
+	//   path: app.log
+	// console stderr
+	// file app.log
+}
+
+func ExampleEncode_omitDefaults() {
+	config := sampleConfig{Level: "info", Servers: []sampleServer{{Host: "localhost", Port: 80}}}
+	err := yaml.Encode(os.Stdout, config, func(opt *yaml.EncodeOption) {
+		opt.Indent = 2
+		opt.OmitDefaults = true
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// servers:
+	// - host: localhost
+	//   port: 80
+}
@@ -0,0 +1,43 @@
+package yaml
+
+import (
+	"strconv"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/shangkuei/gap/codec"
+)
+
+// locate records the lines of the keys of the first yaml document.
+func locate(raw []byte, lines codec.KeyLines) {
+	file, err := parser.ParseBytes(raw, 0)
+	if err != nil || len(file.Docs) == 0 {
+		return
+	}
+	locateNode(file.Docs[0], nil, lines)
+}
+
+func locateNode(node ast.Node, path []string, lines codec.KeyLines) {
+	switch node := node.(type) {
+	case *ast.DocumentNode:
+		locateNode(node.Body, path, lines)
+	case *ast.AnchorNode:
+		locateNode(node.Value, path, lines)
+	case *ast.TagNode:
+		locateNode(node.Value, path, lines)
+	case *ast.MappingNode:
+		for _, value := range node.Values {
+			locateNode(value, path, lines)
+		}
+	case *ast.MappingValueNode:
+		if key := node.Key.GetToken(); key != nil {
+			path := append(path[:len(path):len(path)], key.Value)
+			lines.Set(path, key.Position.Line)
+			locateNode(node.Value, path, lines)
+		}
+	case *ast.SequenceNode:
+		for i, value := range node.Values {
+			locateNode(value, append(path[:len(path):len(path)], strconv.Itoa(i)), lines)
+		}
+	}
+}